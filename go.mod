@@ -2,7 +2,6 @@ module github.com/bcdannyboy/dgws
 
 go 1.19
 
-require (
-	golang.org/x/exp v0.0.0-20240222234643-814bf88cf225 // indirect
-	gonum.org/v1/gonum v0.14.0 // indirect
-)
+require gonum.org/v1/gonum v0.14.0
+
+require golang.org/x/exp v0.0.0-20240222234643-814bf88cf225
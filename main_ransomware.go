@@ -230,6 +230,7 @@ func main_ransomware() {
 		ID:          AntiPhishFilterID,
 		Name:        "Anti-Phishing Filter",
 		Description: "An anti-phishing filter blocks a phishing email.",
+		Control:     true,
 		Probability: &risk.Probability{
 			// We are 90% confident that the anti-phishing filter will block a phishing email at least 80% of the time, and at most 90% of the time
 			// We expect phishing emails very commonly, so we're considering these predictions on a weekly frequency
@@ -266,6 +267,7 @@ func main_ransomware() {
 		ID:          EmployeeReportsPhishID,
 		Name:        "Employee Reports Phishing",
 		Description: "An employee reports a phishing email.",
+		Control:     true,
 		Probability: &risk.Probability{
 			// Based on historical data, we find that employees report phishing emails at least 20% of the time, and at most 60% of the time
 			// The team does phishing assessments once a quarter, so this data is on a quarterly frequency
@@ -302,6 +304,7 @@ func main_ransomware() {
 		ID:          BehavioralControlsCatchAnomalousAccountBehaviorID,
 		Name:        "Behavioral Controls Catch Anomalous Account Behavior",
 		Description: "Behavioral controls are triggered by anomalous account behavior.",
+		Control:     true,
 		Probability: &risk.Probability{
 			// The Threat Detection and Response team just recently got a new behavioral control set, they're not sure how well it will work, but they're confident it will catch anomalous account behavior at least 50% of the time, and at most 80% of the time
 			// The team is less confident in their maximum prediction, so they've marked it at 80% confidence but they're more confident in their minimum prediction, so they've marked it at 90% confidence
@@ -339,6 +342,7 @@ func main_ransomware() {
 		ID:          HostBasedControlsCatchMaliciousActivityOrCodeID,
 		Name:        "Host-Based Controls Catch Malicious Activity or Code",
 		Description: "Host-based controls catch malicious activity or code.",
+		Control:     true,
 		Probability: &risk.Probability{
 			// The Threat Detection & Response team is confident that their host-based controls will catch malicious activity or code at least 60% of the time, and at most 90% of the time
 			// The team is very confident in their predictions, as they've worked dilligently to test and implement these controls, so they've marked both their minimum and maximum predictions at 90% confidence
@@ -376,6 +380,7 @@ func main_ransomware() {
 		ID:          NetworkBasedControlsCatchMaliciousCommandAndControlTrafficID,
 		Name:        "Network-Based Controls Catch Malicious Command and Control Traffic",
 		Description: "Network-based controls catch malicious command and control traffic.",
+		Control:     true,
 		Probability: &risk.Probability{
 			// The Threat Detection & Response team is less confident in their network-based controls, as they have multiple known gaps
 			// The team is confident that their network-based controls will catch malicious command and control traffic at least 40% of the time, and at most 70% of the time
@@ -799,6 +804,15 @@ func main_ransomware() {
 				MinimumImpactEventsConfidence:         0.7,
 				MaximumImpactEvents:                   10000,
 				MaximumImpactEventsConfidence:         0.7,
+				// Customer loss is a secondary loss: it only occurs if customers react to the event, which the organization
+				// expects to happen after most major ransomware events but not all of them
+				Secondary: &risk.SecondaryLoss{
+					Stakeholder:       "Customers",
+					Minimum:           0.5,
+					MinimumConfidence: 0.9,
+					Maximum:           0.9,
+					MaximumConfidence: 0.9,
+				},
 			},
 		},
 		Dependencies: []*risk.Dependency{
@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/utils"
+)
+
+// ControlValue is the marginal value of one control, or of a pair of controls, measured by disabling it.
+type ControlValue struct {
+	Controls             []string `json:"Controls"`
+	ExpectedLoss         float64  `json:"ExpectedLoss"`
+	TopEventProbability  float64  `json:"TopEventProbability"`
+	LossReduction        float64  `json:"LossReduction"`
+	ProbabilityReduction float64  `json:"ProbabilityReduction"`
+	// Interaction is the pair's loss reduction minus the sum of its members' loss reductions.
+	// It is only set for pairs; a positive value means the controls are worth more together than apart.
+	Interaction float64 `json:"Interaction,omitempty"`
+}
+
+// Ablation is the result of disabling each control in turn.
+type Ablation struct {
+	Iterations          int             `json:"Iterations"`
+	LossUnit            string          `json:"LossUnit"`
+	TopEvent            string          `json:"TopEvent"`
+	ExpectedLoss        float64         `json:"ExpectedLoss"`
+	TopEventProbability float64         `json:"TopEventProbability"`
+	Controls            []*ControlValue `json:"Controls"`
+	Pairs               []*ControlValue `json:"Pairs,omitempty"`
+}
+
+// AblateControls disables each event marked as a control in turn (and each pair of controls if pairs is set),
+// reruns the simulation with common random numbers and ranks the controls by the reduction in
+// expected yearly loss in lossUnit and in the top event's probability that they provide.
+func AblateControls(events []*risk.Event, topEventID int, lossUnit string, iterations int, pairs bool) (*Ablation, error) {
+	return AblateControlsSeeded(events, topEventID, lossUnit, iterations, pairs, time.Now().UnixNano())
+}
+
+// AblateControlsSeeded is AblateControls with a fixed seed.
+func AblateControlsSeeded(events []*risk.Event, topEventID int, lossUnit string, iterations int, pairs bool, seed int64) (*Ablation, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	topEvent := utils.FindEvent(topEventID, events)
+	if topEvent == nil {
+		return nil, fmt.Errorf("top event %d not found", topEventID)
+	}
+
+	var controls []*risk.Event
+	for _, event := range events {
+		if event.Control {
+			controls = append(controls, event)
+		}
+	}
+	if len(controls) == 0 {
+		return nil, fmt.Errorf("no events are marked as controls")
+	}
+
+	evaluate := func(disabled ...*risk.Event) (*ControlValue, error) {
+		sim, err := newSimulation(events, seed)
+		if err != nil {
+			return nil, err
+		}
+		value := &ControlValue{}
+		for _, control := range disabled {
+			sim.forced[control.ID] = false
+			value.Controls = append(value.Controls, control.Name)
+		}
+		probabilities, impacts := expectedOutcomes(sim, iterations)
+		value.ExpectedLoss = impacts[lossUnit]
+		value.TopEventProbability = probabilities[topEventID]
+		return value, nil
+	}
+
+	baseline, err := evaluate()
+	if err != nil {
+		return nil, err
+	}
+	ablation := &Ablation{
+		Iterations:          iterations,
+		LossUnit:            lossUnit,
+		TopEvent:            topEvent.Name,
+		ExpectedLoss:        baseline.ExpectedLoss,
+		TopEventProbability: baseline.TopEventProbability,
+	}
+
+	reduction := make(map[int]float64)
+	for _, control := range controls {
+		value, err := evaluate(control)
+		if err != nil {
+			return nil, err
+		}
+		value.LossReduction = value.ExpectedLoss - baseline.ExpectedLoss
+		value.ProbabilityReduction = value.TopEventProbability - baseline.TopEventProbability
+		reduction[control.ID] = value.LossReduction
+		ablation.Controls = append(ablation.Controls, value)
+	}
+
+	if pairs {
+		for i := 0; i < len(controls); i++ {
+			for j := i + 1; j < len(controls); j++ {
+				value, err := evaluate(controls[i], controls[j])
+				if err != nil {
+					return nil, err
+				}
+				value.LossReduction = value.ExpectedLoss - baseline.ExpectedLoss
+				value.ProbabilityReduction = value.TopEventProbability - baseline.TopEventProbability
+				value.Interaction = value.LossReduction - reduction[controls[i].ID] - reduction[controls[j].ID]
+				ablation.Pairs = append(ablation.Pairs, value)
+			}
+		}
+	}
+
+	rankControlValues(ablation.Controls)
+	rankControlValues(ablation.Pairs)
+
+	return ablation, nil
+}
+
+// rankControlValues sorts by loss reduction, then by top event probability reduction, largest first.
+func rankControlValues(values []*ControlValue) {
+	sort.SliceStable(values, func(i, j int) bool {
+		if values[i].LossReduction != values[j].LossReduction {
+			return values[i].LossReduction > values[j].LossReduction
+		}
+		return values[i].ProbabilityReduction > values[j].ProbabilityReduction
+	})
+}
@@ -0,0 +1,245 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// SignificanceLevel is the p-value below which a difference between two models is reported as significant.
+const SignificanceLevel = 0.05
+
+// reportedPercentiles are the percentiles reported for each impact distribution.
+var reportedPercentiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+// EventDifference is the difference in an event's yearly probability between two models.
+type EventDifference struct {
+	EventID                int     `json:"EventID"`
+	Name                   string  `json:"Name"`
+	InBaseline             bool    `json:"InBaseline"`
+	InAlternative          bool    `json:"InAlternative"`
+	BaselineProbability    float64 `json:"BaselineProbability"`
+	AlternativeProbability float64 `json:"AlternativeProbability"`
+	Difference             float64 `json:"Difference"`
+	StandardError          float64 `json:"StandardError"`
+	PValue                 float64 `json:"PValue"`
+	Significant            bool    `json:"Significant"`
+}
+
+// PercentileDifference is the difference between two models at one percentile of an impact distribution.
+type PercentileDifference struct {
+	Percentile  float64 `json:"Percentile"`
+	Baseline    float64 `json:"Baseline"`
+	Alternative float64 `json:"Alternative"`
+	Difference  float64 `json:"Difference"`
+}
+
+// ImpactDifference is the difference in the per-iteration distribution of an impact unit between two models.
+type ImpactDifference struct {
+	Unit              string                  `json:"Unit"`
+	BaselineMean      float64                 `json:"BaselineMean"`
+	AlternativeMean   float64                 `json:"AlternativeMean"`
+	MeanDifference    float64                 `json:"MeanDifference"`
+	BaselineStdDev    float64                 `json:"BaselineStdDev"`
+	AlternativeStdDev float64                 `json:"AlternativeStdDev"`
+	StandardError     float64                 `json:"StandardError"`
+	PValue            float64                 `json:"PValue"`
+	Significant       bool                    `json:"Significant"`
+	Percentiles       []*PercentileDifference `json:"Percentiles"`
+}
+
+// Comparison is the result of comparing two variants of a model.
+type Comparison struct {
+	Iterations int                 `json:"Iterations"`
+	Events     []*EventDifference  `json:"Events"`
+	Impacts    []*ImpactDifference `json:"Impacts"`
+}
+
+// Compare simulates a baseline and an alternative model with common random numbers and
+// reports the per-event probability and per-impact distribution differences (alternative minus baseline).
+// Events are matched between the models by ID, so an alternative should reuse the baseline's event IDs.
+// Significance is assessed with a paired z-test on the per-iteration differences.
+func Compare(baseline, alternative []*risk.Event, iterations int) (*Comparison, error) {
+	return CompareSeeded(baseline, alternative, iterations, time.Now().UnixNano())
+}
+
+// CompareSeeded is Compare with a fixed seed.
+func CompareSeeded(baseline, alternative []*risk.Event, iterations int, seed int64) (*Comparison, error) {
+	if iterations < 2 {
+		return nil, fmt.Errorf("at least 2 iterations are required to compare models, got %d", iterations)
+	}
+
+	baseSim, err := newSimulation(baseline, seed)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing baseline model: %w", err)
+	}
+	altSim, err := newSimulation(alternative, seed)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing alternative model: %w", err)
+	}
+
+	// Paired occurrence counts per event
+	baseCount := make(map[int]int)
+	altCount := make(map[int]int)
+	baseOnly := make(map[int]int)
+	altOnly := make(map[int]int)
+
+	// Per-iteration impact totals per unit
+	baseImpacts := make(map[string][]float64)
+	altImpacts := make(map[string][]float64)
+	record := func(series map[string][]float64, i int, totals map[string]float64) {
+		for unit, value := range totals {
+			if _, ok := series[unit]; !ok {
+				series[unit] = make([]float64, iterations)
+			}
+			series[unit][i] = value
+		}
+	}
+
+	for i := 0; i < iterations; i++ {
+		baseOccurred, baseEventImpacts := baseSim.iterate(i)
+		altOccurred, altEventImpacts := altSim.iterate(i)
+
+		for eventID, happened := range baseOccurred {
+			if happened {
+				baseCount[eventID]++
+				if !altOccurred[eventID] {
+					baseOnly[eventID]++
+				}
+			}
+		}
+		for eventID, happened := range altOccurred {
+			if happened {
+				altCount[eventID]++
+				if !baseOccurred[eventID] {
+					altOnly[eventID]++
+				}
+			}
+		}
+
+		record(baseImpacts, i, totalImpacts(baseEventImpacts))
+		record(altImpacts, i, totalImpacts(altEventImpacts))
+	}
+
+	n := float64(iterations)
+	comparison := &Comparison{Iterations: iterations}
+
+	for _, e := range mergeEvents(baseline, alternative) {
+		_, inBase := baseSim.probabilities[e.ID]
+		_, inAlt := altSim.probabilities[e.ID]
+
+		// Per-iteration differences are -1, 0 or 1, so their moments follow from the counts.
+		mean := float64(altOnly[e.ID]-baseOnly[e.ID]) / n
+		meanSquare := float64(altOnly[e.ID]+baseOnly[e.ID]) / n
+		variance := (meanSquare - mean*mean) * n / (n - 1)
+		se, p := pairedTest(mean, variance, n)
+
+		comparison.Events = append(comparison.Events, &EventDifference{
+			EventID:                e.ID,
+			Name:                   e.Name,
+			InBaseline:             inBase,
+			InAlternative:          inAlt,
+			BaselineProbability:    float64(baseCount[e.ID]) / n,
+			AlternativeProbability: float64(altCount[e.ID]) / n,
+			Difference:             mean,
+			StandardError:          se,
+			PValue:                 p,
+			Significant:            p < SignificanceLevel,
+		})
+	}
+
+	units := make(map[string]bool)
+	for unit := range baseImpacts {
+		units[unit] = true
+	}
+	for unit := range altImpacts {
+		units[unit] = true
+	}
+	for _, unit := range sortedKeys(units) {
+		base := seriesOrZero(baseImpacts[unit], iterations)
+		alt := seriesOrZero(altImpacts[unit], iterations)
+
+		diffs := make([]float64, iterations)
+		for i := range diffs {
+			diffs[i] = alt[i] - base[i]
+		}
+		mean, variance := stat.MeanVariance(diffs, nil)
+		se, p := pairedTest(mean, variance, n)
+
+		baseMean, baseStd := stat.MeanStdDev(base, nil)
+		altMean, altStd := stat.MeanStdDev(alt, nil)
+
+		sort.Float64s(base)
+		sort.Float64s(alt)
+		var percentiles []*PercentileDifference
+		for _, q := range reportedPercentiles {
+			b := stat.Quantile(q, stat.Empirical, base, nil)
+			a := stat.Quantile(q, stat.Empirical, alt, nil)
+			percentiles = append(percentiles, &PercentileDifference{Percentile: q, Baseline: b, Alternative: a, Difference: a - b})
+		}
+
+		comparison.Impacts = append(comparison.Impacts, &ImpactDifference{
+			Unit:              unit,
+			BaselineMean:      baseMean,
+			AlternativeMean:   altMean,
+			MeanDifference:    mean,
+			BaselineStdDev:    baseStd,
+			AlternativeStdDev: altStd,
+			StandardError:     se,
+			PValue:            p,
+			Significant:       p < SignificanceLevel,
+			Percentiles:       percentiles,
+		})
+	}
+
+	return comparison, nil
+}
+
+// pairedTest returns the standard error of a mean paired difference and its two-sided p-value.
+func pairedTest(mean, variance, n float64) (float64, float64) {
+	se := math.Sqrt(math.Max(variance, 0) / n)
+	if se == 0 {
+		if mean == 0 {
+			return 0, 1
+		}
+		return 0, 0
+	}
+	z := math.Abs(mean / se)
+	return se, 2 * (1 - distuv.UnitNormal.CDF(z))
+}
+
+// mergeEvents returns the baseline events followed by the alternative events whose IDs are not in the baseline.
+func mergeEvents(baseline, alternative []*risk.Event) []*risk.Event {
+	seen := make(map[int]bool)
+	var merged []*risk.Event
+	for _, events := range [][]*risk.Event{baseline, alternative} {
+		for _, e := range events {
+			if !seen[e.ID] {
+				seen[e.ID] = true
+				merged = append(merged, e)
+			}
+		}
+	}
+	return merged
+}
+
+func seriesOrZero(series []float64, n int) []float64 {
+	if series == nil {
+		return make([]float64, n)
+	}
+	return series
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,227 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/utils"
+)
+
+// MaxCutSets is the largest number of terms kept while expanding cut or path sets before giving up.
+const MaxCutSets = 100000
+
+// Literal is a basic event's own trial, succeeding (the event fires) or failing (e.g. a control that does not catch).
+type Literal struct {
+	EventID int    `json:"EventID"`
+	Name    string `json:"Name"`
+	Occurs  bool   `json:"Occurs"`
+}
+
+// CutSet is a minimal combination of basic event outcomes, with the probability that all of them happen together.
+type CutSet struct {
+	Literals    []*Literal `json:"Literals"`
+	Probability float64    `json:"Probability"`
+}
+
+// CutSets holds the minimal cut sets and minimal path sets of a top event, ranked by probability.
+type CutSets struct {
+	TopEvent string    `json:"TopEvent"`
+	CutSets  []*CutSet `json:"CutSets"`
+	PathSets []*CutSet `json:"PathSets"`
+	// RareEventApproximation is the sum of the cut set probabilities, an upper bound on the top event's probability.
+	RareEventApproximation float64 `json:"RareEventApproximation"`
+}
+
+// MinimalCutSets reads the dependency graph as a fault tree and computes the minimal cut sets of the top event
+// (minimal combinations of outcomes that make it occur) and its minimal path sets (minimal combinations that
+// guarantee it does not occur). An event occurs when its own trial succeeds, every Happens dependency occurs
+// and every other dependency does not occur; dependencies that must not occur are treated as strict requirements.
+// Set probabilities multiply the events' initial probability estimates, which are independent by construction.
+func MinimalCutSets(events []*risk.Event, topEventID int) (*CutSets, error) {
+	return MinimalCutSetsSeeded(events, topEventID, time.Now().UnixNano())
+}
+
+// MinimalCutSetsSeeded is MinimalCutSets with a fixed seed for the initial probability estimates.
+func MinimalCutSetsSeeded(events []*risk.Event, topEventID int, seed int64) (*CutSets, error) {
+	topEvent := utils.FindEvent(topEventID, events)
+	if topEvent == nil {
+		return nil, fmt.Errorf("top event %d not found", topEventID)
+	}
+	sim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	expander := &dnfExpander{events: events, memo: make(map[dnfKey]dnf), visiting: make(map[int]bool)}
+	cuts, err := expander.expand(topEventID, true)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := expander.expand(topEventID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CutSets{
+		TopEvent: topEvent.Name,
+		CutSets:  expander.cutSets(cuts, sim.probabilities),
+		PathSets: expander.cutSets(paths, sim.probabilities),
+	}
+	for _, cut := range result.CutSets {
+		result.RareEventApproximation += cut.Probability
+	}
+
+	return result, nil
+}
+
+// term is a conjunction of basic event outcomes keyed by event ID.
+type term map[int]bool
+
+// dnf is a disjunction of terms.
+type dnf []term
+
+type dnfKey struct {
+	eventID int
+	occurs  bool
+}
+
+// dnfExpander expands event occurrence and non-occurrence into minimal disjunctive normal form.
+type dnfExpander struct {
+	events   []*risk.Event
+	memo     map[dnfKey]dnf
+	visiting map[int]bool
+}
+
+// expand returns the minimal DNF over basic outcomes for the event occurring (or not occurring).
+//
+//	occurs(X)    = x AND occurs(D) for Happens dependencies AND not occurs(D) for the others
+//	notOccurs(X) = not x OR notOccurs(D) for Happens dependencies OR occurs(D) for the others
+func (e *dnfExpander) expand(eventID int, occurs bool) (dnf, error) {
+	key := dnfKey{eventID, occurs}
+	if result, ok := e.memo[key]; ok {
+		return result, nil
+	}
+	event := utils.FindEvent(eventID, e.events)
+	if event == nil {
+		return nil, fmt.Errorf("dependency on unknown event %d", eventID)
+	}
+	if e.visiting[eventID] {
+		return nil, fmt.Errorf("dependency cycle through event %d (%s)", eventID, event.Name)
+	}
+	e.visiting[eventID] = true
+	defer delete(e.visiting, eventID)
+
+	result := dnf{term{eventID: occurs}}
+	for _, dependency := range event.Dependencies {
+		sub, err := e.expand(dependency.DependsOnEventID, dependency.Happens == occurs)
+		if err != nil {
+			return nil, err
+		}
+		if occurs {
+			result = and(result, sub)
+		} else {
+			result = minimize(append(result, sub...))
+		}
+		if len(result) > MaxCutSets {
+			return nil, fmt.Errorf("more than %d sets while expanding event %d (%s)", MaxCutSets, eventID, event.Name)
+		}
+	}
+
+	e.memo[key] = result
+	return result, nil
+}
+
+// and returns the minimal DNF of the conjunction of two DNFs, dropping contradictory terms.
+func and(a, b dnf) dnf {
+	var result dnf
+	for _, x := range a {
+	combine:
+		for _, y := range b {
+			merged := make(term, len(x)+len(y))
+			for id, occurs := range x {
+				merged[id] = occurs
+			}
+			for id, occurs := range y {
+				if existing, ok := merged[id]; ok && existing != occurs {
+					continue combine
+				}
+				merged[id] = occurs
+			}
+			result = append(result, merged)
+		}
+	}
+	return minimize(result)
+}
+
+// minimize removes duplicate terms and terms that contain another term.
+func minimize(terms dnf) dnf {
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) < len(terms[j]) })
+	var kept dnf
+	for _, t := range terms {
+		absorbed := false
+		for _, k := range kept {
+			if contains(t, k) {
+				absorbed = true
+				break
+			}
+		}
+		if !absorbed {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// contains reports whether every outcome of sub is in t.
+func contains(t, sub term) bool {
+	if len(sub) > len(t) {
+		return false
+	}
+	for id, occurs := range sub {
+		if existing, ok := t[id]; !ok || existing != occurs {
+			return false
+		}
+	}
+	return true
+}
+
+// cutSets converts terms into cut sets with their probabilities, ranked by probability.
+func (e *dnfExpander) cutSets(terms dnf, probabilities map[int]float64) []*CutSet {
+	var sets []*CutSet
+	for _, t := range terms {
+		set := &CutSet{Probability: 1}
+		ids := make([]int, 0, len(t))
+		for id := range t {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			literal := &Literal{EventID: id, Name: utils.FindEvent(id, e.events).Name, Occurs: t[id]}
+			set.Literals = append(set.Literals, literal)
+			if literal.Occurs {
+				set.Probability *= probabilities[id]
+			} else {
+				set.Probability *= 1 - probabilities[id]
+			}
+		}
+		sets = append(sets, set)
+	}
+	sort.SliceStable(sets, func(i, j int) bool { return sets[i].Probability > sets[j].Probability })
+	return sets
+}
+
+// String describes the cut set, e.g. "Phishing Attempt AND NOT Anti-Phishing Filter".
+func (c *CutSet) String() string {
+	parts := make([]string, len(c.Literals))
+	for i, literal := range c.Literals {
+		parts[i] = literal.Name
+		if !literal.Occurs {
+			parts[i] = "NOT " + literal.Name
+		}
+	}
+	return strings.Join(parts, " AND ") + " (p=" + strconv.FormatFloat(c.Probability, 'g', 4, 64) + ")"
+}
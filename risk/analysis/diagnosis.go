@@ -0,0 +1,156 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/utils"
+)
+
+// Explanation is a joint outcome of every upstream event with its probability given that the diagnosed event occurred.
+type Explanation struct {
+	Outcomes    []*Literal `json:"Outcomes"`
+	Probability float64    `json:"Probability"`
+}
+
+// Diagnosis explains how a diagnosed event comes about.
+type Diagnosis struct {
+	Iterations int    `json:"Iterations"`
+	Event      string `json:"Event"`
+	// EventProbability is the estimated yearly probability of the diagnosed event.
+	EventProbability float64 `json:"EventProbability"`
+	// MostProbableExplanation is the most probable joint outcome of the upstream events.
+	MostProbableExplanation *Explanation `json:"MostProbableExplanation"`
+	// Explanations are the most probable joint outcomes, ranked by probability.
+	Explanations []*Explanation `json:"Explanations"`
+	// Upstream is each upstream event's probability given that the diagnosed event occurred.
+	Upstream []*EventPosterior `json:"Upstream"`
+}
+
+// Diagnose conditions the model on the event occurring and reports which upstream events (those it depends on,
+// directly or transitively) most often lead to it: the most probable explanation, the next most probable joint
+// outcomes, up to the given number of explanations, and the posterior probability of each upstream event.
+// It uses likelihood weighting, so rare events are diagnosed without discarding the iterations in which they
+// do not occur.
+func Diagnose(events []*risk.Event, eventID int, explanations, iterations int) (*Diagnosis, error) {
+	return DiagnoseSeeded(events, eventID, explanations, iterations, time.Now().UnixNano())
+}
+
+// DiagnoseSeeded is Diagnose with a fixed seed.
+func DiagnoseSeeded(events []*risk.Event, eventID int, explanations, iterations int, seed int64) (*Diagnosis, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	if explanations < 1 {
+		return nil, fmt.Errorf("at least 1 explanation is required, got %d", explanations)
+	}
+	event := utils.FindEvent(eventID, events)
+	if event == nil {
+		return nil, fmt.Errorf("event %d not found", eventID)
+	}
+	sim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, err
+	}
+	if err := sim.setEvidence(Evidence{eventID: true}); err != nil {
+		return nil, err
+	}
+
+	upstream := ancestors(events, eventID)
+	outcomes := make(map[string]float64)
+	occurrences := make(map[int]float64)
+	var totalWeight float64
+
+	for i := 0; i < iterations; i++ {
+		eventsOccurred, _, weight := sim.iterateWeighted(i)
+		if weight == 0 {
+			continue
+		}
+		totalWeight += weight
+
+		key := make([]byte, len(upstream))
+		for j, id := range upstream {
+			key[j] = '0'
+			if eventsOccurred[id] {
+				key[j] = '1'
+				occurrences[id] += weight
+			}
+		}
+		outcomes[string(key)] += weight
+	}
+	if totalWeight == 0 {
+		return nil, fmt.Errorf("event %d (%s) never occurred", event.ID, event.Name)
+	}
+
+	diagnosis := &Diagnosis{
+		Iterations:       iterations,
+		Event:            event.Name,
+		EventProbability: totalWeight / float64(iterations),
+	}
+	for _, id := range upstream {
+		diagnosis.Upstream = append(diagnosis.Upstream, &EventPosterior{
+			EventID:     id,
+			Name:        utils.FindEvent(id, events).Name,
+			Probability: occurrences[id] / totalWeight,
+		})
+	}
+
+	// Sorting the keys first breaks ties in probability the same way in every run.
+	keys := make([]string, 0, len(outcomes))
+	for key := range outcomes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		explanation := &Explanation{Probability: outcomes[key] / totalWeight}
+		for j, id := range upstream {
+			explanation.Outcomes = append(explanation.Outcomes, &Literal{
+				EventID: id,
+				Name:    utils.FindEvent(id, events).Name,
+				Occurs:  key[j] == '1',
+			})
+		}
+		diagnosis.Explanations = append(diagnosis.Explanations, explanation)
+	}
+	sort.SliceStable(diagnosis.Explanations, func(i, j int) bool {
+		return diagnosis.Explanations[i].Probability > diagnosis.Explanations[j].Probability
+	})
+	if len(diagnosis.Explanations) > explanations {
+		diagnosis.Explanations = diagnosis.Explanations[:explanations]
+	}
+	if len(diagnosis.Explanations) > 0 {
+		diagnosis.MostProbableExplanation = diagnosis.Explanations[0]
+	}
+
+	return diagnosis, nil
+}
+
+// ancestors returns the IDs of every event the given event depends on, directly or transitively, in model order.
+func ancestors(events []*risk.Event, eventID int) []int {
+	found := make(map[int]bool)
+	queue := []int{eventID}
+	for len(queue) > 0 {
+		event := utils.FindEvent(queue[0], events)
+		queue = queue[1:]
+		if event == nil {
+			continue
+		}
+		for _, dependency := range event.Dependencies {
+			id := dependency.DependsOnEventID
+			if !found[id] && id != eventID && utils.FindEvent(id, events) != nil {
+				found[id] = true
+				queue = append(queue, id)
+			}
+		}
+	}
+
+	var ordered []int
+	for _, event := range events {
+		if found[event.ID] {
+			ordered = append(ordered, event.ID)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,450 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/statistics"
+	"github.com/bcdannyboy/dgws/risk/utils"
+	"golang.org/x/exp/rand"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Random number streams. Every random draw in a seeded simulation is keyed by
+// the seed, the iteration, the event ID and the stream, so two models that share
+// events see the same random numbers for those events (common random numbers).
+const (
+	streamInitialization = iota
+	streamOccurrence
+	streamTrace
+	streamSecondary
+	streamMonetization
+	streamTiming
+)
+
+// simulation holds the state shared by every iteration of a seeded run.
+type simulation struct {
+	events        []*risk.Event
+	probabilities map[int]float64
+	seed          uint64
+
+	// rates holds the expected yearly number of occurrences of each frequency event,
+	// and subtrees the events that depend on it, which are simulated once per occurrence.
+	rates    map[int]float64
+	subtrees map[int][]*risk.Event
+
+	// reactions holds the probability that the stakeholders of each secondary loss react to one occurrence.
+	reactions map[impactKey]float64
+
+	// profiled is set when an event has a seasonality or a trend, so that its estimates vary between periods.
+	profiled bool
+
+	// propagation is PropagateEpisodes unless set with setPropagation.
+	propagation Propagation
+
+	// forced events always (true) or never (false) occur regardless of their probability,
+	// e.g. controls switched off to measure inherent risk.
+	forced map[int]bool
+
+	// evidence holds observed outcomes that iterateWeighted conditions on.
+	evidence Evidence
+
+	// tracer, when set, records the iterations it samples.
+	tracer *Tracer
+}
+
+// newSimulation validates the events and estimates each event's initial probability
+// from its own seeded random stream.
+func newSimulation(events []*risk.Event, seed int64) (*simulation, error) {
+	s := &simulation{
+		events:        events,
+		probabilities: make(map[int]float64),
+		rates:         make(map[int]float64),
+		subtrees:      make(map[int][]*risk.Event),
+		reactions:     make(map[impactKey]float64),
+		seed:          uint64(seed),
+		forced:        make(map[int]bool),
+		propagation:   PropagateEpisodes,
+	}
+
+	for _, event := range events {
+		if event == nil {
+			return nil, fmt.Errorf("nil event in event list")
+		}
+		if event.Probability == nil && event.Frequency == nil {
+			return nil, fmt.Errorf("event %d (%s) has no probability", event.ID, event.Name)
+		}
+		if event.Probability != nil && event.Frequency != nil {
+			return nil, fmt.Errorf("event %d (%s) has both a probability and a frequency", event.ID, event.Name)
+		}
+		if _, ok := s.probabilities[event.ID]; ok {
+			return nil, fmt.Errorf("duplicate event ID %d (%s)", event.ID, event.Name)
+		}
+		if err := validateProfile(event); err != nil {
+			return nil, err
+		}
+		if seasonality, trend := profile(event); seasonality != nil || trend != nil {
+			s.profiled = true
+		}
+		secondary := s.rng(-1, event.ID, streamSecondary)
+		for _, impact := range event.Impact {
+			if _, err := utils.PeriodsPerYear(impact.ExpectedFrequency); err != nil {
+				return nil, fmt.Errorf("event %d (%s) impact %s: %w", event.ID, event.Name, impact.Name, err)
+			}
+			if reaction := impact.Secondary; reaction != nil {
+				if reaction.Minimum < 0 || reaction.Maximum > 1 || reaction.Minimum > reaction.Maximum {
+					return nil, fmt.Errorf("event %d (%s) impact %s: reaction probability must satisfy 0 <= minimum <= maximum <= 1", event.ID, event.Name, impact.Name)
+				}
+				key := impactKey{event.ID, impact.ImpactID}
+				s.reactions[key] = initialProbability(secondary, reaction.Minimum, reaction.MinimumConfidence, reaction.Maximum, reaction.MaximumConfidence)
+			}
+		}
+
+		if event.Frequency != nil {
+			rate, p, err := yearlyRate(event)
+			if err != nil {
+				return nil, err
+			}
+			s.rates[event.ID] = rate
+			s.probabilities[event.ID] = p
+			s.subtrees[event.ID] = subtree(events, event.ID)
+			continue
+		}
+
+		rng := s.rng(-1, event.ID, streamInitialization)
+		scaledMin, err := utils.AnnualProbability(event.Probability.Minimum, event.Probability.Kind, event.Probability.ExpectedFrequency)
+		if err != nil {
+			return nil, fmt.Errorf("event %d (%s) minimum probability: %w", event.ID, event.Name, err)
+		}
+		scaledMax, err := utils.AnnualProbability(event.Probability.Maximum, event.Probability.Kind, event.Probability.ExpectedFrequency)
+		if err != nil {
+			return nil, fmt.Errorf("event %d (%s) maximum probability: %w", event.ID, event.Name, err)
+		}
+		s.probabilities[event.ID] = initialProbability(rng, scaledMin, event.Probability.MinimumConfidence, scaledMax, event.Probability.MaximumConfidence)
+	}
+
+	return s, nil
+}
+
+// initialProbability estimates a probability from its bounds and their confidences.
+func initialProbability(rng *rand.Rand, min, minConfidence, max, maxConfidence float64) float64 {
+	initMin := statistics.GenerateBetaSampleFrom(rng, min, minConfidence)
+	initMax := statistics.GenerateBetaSampleFrom(rng, max, maxConfidence)
+	sampleProb := statistics.GenerateLHSSamplesFrom(rng, initMin, initMax, 100)
+	return clampProbability(calcAvg(sampleProb))
+}
+
+// impactKey identifies an impact of an event.
+type impactKey struct {
+	eventID, impactID int
+}
+
+// iterate simulates every event, in order, for the given iteration.
+// It returns which events occurred and the impacts produced by each event that occurred.
+func (s *simulation) iterate(iteration int) (map[int]bool, map[int]map[string]float64) {
+	o := s.iterateOutcome(iteration)
+	return o.occurred, o.impacts
+}
+
+// iterateWeighted is iterate with likelihood weighting: events with evidence are set to their observed
+// outcome instead of being sampled, and the returned weight is the likelihood of that evidence given
+// the outcomes sampled before it. Without evidence the weight is 1.
+func (s *simulation) iterateWeighted(iteration int) (map[int]bool, map[int]map[string]float64, float64) {
+	o := s.iterateOutcome(iteration)
+	return o.occurred, o.impacts, o.weight
+}
+
+// iterateOutcome simulates an iteration as a whole year and returns its full outcome.
+func (s *simulation) iterateOutcome(iteration int) *outcome {
+	return s.iteratePeriod(iteration, wholeYear)
+}
+
+// iteratePeriod simulates an iteration over a period, with the probabilities and rates of its events
+// scaled to the period by their seasonality and trend, and returns its full outcome.
+// Every occurrence of an event produces its primary impacts, so they are scaled by the event's count;
+// secondary losses are added for each occurrence their stakeholders reacted to.
+func (s *simulation) iteratePeriod(iteration int, at period) *outcome {
+	o := &outcome{
+		counts:    make(map[int]int, len(s.events)),
+		secondary: make(map[int]map[string]float64),
+		weight:    1,
+	}
+	o.probabilities, o.rates = s.estimates(at)
+	if s.tracer.traces(s, iteration) {
+		o.trace = &IterationTrace{Iteration: iteration}
+	}
+
+	if s.propagation == PropagateThinning {
+		s.thin(iteration, o)
+	} else {
+		s.episode(iteration, nil, s.events, make(map[int]bool, len(s.events)), o)
+	}
+
+	o.occurred = make(map[int]bool, len(s.events))
+	o.impacts = make(map[int]map[string]float64)
+	for _, event := range s.events {
+		count := o.counts[event.ID]
+		o.occurred[event.ID] = count > 0
+		if count > 0 {
+			impacts := calculateImpacts(event, s.probabilities)
+			for unit := range impacts {
+				impacts[unit] *= float64(count)
+			}
+			for unit, value := range o.secondary[event.ID] {
+				impacts[unit] += value
+			}
+			o.impacts[event.ID] = impacts
+		}
+	}
+
+	if o.trace != nil {
+		o.trace.Weight = o.weight
+		s.tracer.write(o.trace)
+	}
+
+	return o
+}
+
+// outcome is the result of one iteration, accumulated across its episodes.
+type outcome struct {
+	occurred map[int]bool
+	counts   map[int]int
+	// impacts holds every impact of each event that occurred, secondary losses included,
+	// and secondary the secondary losses alone.
+	impacts   map[int]map[string]float64
+	secondary map[int]map[string]float64
+	weight    float64
+	trace     *IterationTrace
+
+	// probabilities and rates are the estimates of the events over the simulated period.
+	probabilities map[int]float64
+	rates         map[int]float64
+}
+
+// episode simulates the given events, in order, with the outcomes in eventsOccurred already decided.
+// The events that depend on a frequency event are skipped and simulated in a separate episode for each of
+// its occurrences, once every other event of this episode is decided. path holds the occurrence numbers
+// of the enclosing episodes and keys their random draws; the top-level episode of an iteration has none.
+func (s *simulation) episode(iteration int, path []int, events []*risk.Event, eventsOccurred map[int]bool, o *outcome) {
+	type occurrences struct {
+		event   *risk.Event
+		count   int
+		subtree []*risk.Event
+	}
+	var deferred []occurrences
+	included := make(map[int]bool, len(events))
+	for _, event := range events {
+		included[event.ID] = true
+	}
+	claimed := make(map[int]bool)
+
+	for _, event := range events {
+		if claimed[event.ID] {
+			continue
+		}
+
+		p := UpdateEventProbabilityWithDependency(event, eventsOccurred, o.probabilities)
+		count := s.decide(iteration, path, event, p, eventsOccurred, 1, o)
+
+		if event.Frequency != nil {
+			var descendants []*risk.Event
+			for _, descendant := range s.subtrees[event.ID] {
+				if included[descendant.ID] && !claimed[descendant.ID] {
+					claimed[descendant.ID] = true
+					descendants = append(descendants, descendant)
+				}
+			}
+			if count > 0 && len(descendants) > 0 {
+				deferred = append(deferred, occurrences{event: event, count: count, subtree: descendants})
+			}
+		}
+	}
+
+	for _, d := range deferred {
+		for k := 1; k <= d.count; k++ {
+			occurred := make(map[int]bool, len(eventsOccurred))
+			for eventID, happened := range eventsOccurred {
+				occurred[eventID] = happened
+			}
+			occurred[d.event.ID] = true
+			s.episode(iteration, append(path[:len(path):len(path)], k), d.subtree, occurred, o)
+		}
+	}
+}
+
+// decide draws how many times an event occurs given p, its probability adjusted for the outcomes decided
+// before it, records it in eventsOccurred and o, and returns it. An event without a Frequency has the given
+// number of trials, each of which it passes with probability p. Events forced to occur pass every trial and
+// occur at least once; observed events occur once.
+func (s *simulation) decide(iteration int, path []int, event *risk.Event, p float64, eventsOccurred map[int]bool, trials int, o *outcome) int {
+	mode, draw := TraceSampled, 0.0
+	if happened, ok := s.forced[event.ID]; ok {
+		mode, p = TraceForced, 0
+		if happened {
+			p = 1
+		}
+	}
+
+	count := 0
+	if observed, ok := s.evidence[event.ID]; ok {
+		mode = TraceObserved
+		if observed {
+			o.weight *= p
+			count = 1
+		} else {
+			o.weight *= 1 - p
+		}
+	} else if mode == TraceForced {
+		if p == 1 {
+			count = trials
+			if count < 1 {
+				count = 1
+			}
+		}
+	} else if event.Frequency != nil {
+		count = s.count(iteration, path, event, p, o)
+	} else if trials == 1 {
+		draw = s.uniformAt(iteration, path, event.ID, streamOccurrence)
+		if draw < p {
+			count = 1
+		}
+	} else if trials > 1 && p > 0 {
+		binomial := distuv.Binomial{N: float64(trials), P: p, Src: s.rngAt(iteration, path, event.ID, streamOccurrence)}
+		count = int(binomial.Rand())
+	}
+
+	eventsOccurred[event.ID] = count > 0
+	o.counts[event.ID] += count
+	secondary := s.react(iteration, path, event, count)
+	for unit, value := range secondary {
+		if o.secondary[event.ID] == nil {
+			o.secondary[event.ID] = make(map[string]float64)
+		}
+		o.secondary[event.ID][unit] += value
+	}
+
+	if o.trace != nil {
+		var impacts map[string]float64
+		if count > 0 {
+			impacts = calculateImpacts(event, s.probabilities)
+			for unit := range impacts {
+				impacts[unit] *= float64(count)
+			}
+		}
+		o.trace.Events = append(o.trace.Events, &EventTrace{
+			EventID:             event.ID,
+			Name:                event.Name,
+			Occurrence:          path,
+			Probability:         o.probabilities[event.ID],
+			AdjustedProbability: p,
+			Mode:                mode,
+			Draw:                draw,
+			Occurred:            count > 0,
+			Occurrences:         count,
+			Impacts:             impacts,
+			SecondaryImpacts:    secondary,
+		})
+	}
+
+	return count
+}
+
+// react draws, for each secondary loss of an event that occurred count times, how many of those occurrences
+// its stakeholders reacted to and the magnitude of each reaction, and returns the resulting secondary losses per unit.
+func (s *simulation) react(iteration int, path []int, event *risk.Event, count int) map[string]float64 {
+	if count == 0 {
+		return nil
+	}
+	var losses map[string]float64
+	var rng *rand.Rand
+	for _, impact := range event.Impact {
+		if impact.Secondary == nil {
+			continue
+		}
+		if rng == nil {
+			rng = s.rngAt(iteration, path, event.ID, streamSecondary)
+		}
+		p := s.reactions[impactKey{event.ID, impact.ImpactID}]
+		reactions := 0
+		if p > 0 {
+			reactions = int(distuv.Binomial{N: float64(count), P: p, Src: rng}.Rand())
+		}
+		if reactions > 0 {
+			if losses == nil {
+				losses = make(map[string]float64)
+			}
+			for j := 0; j < reactions; j++ {
+				losses[impact.Unit] += sampleImpact(rng, impact, s.probabilities[event.ID])
+			}
+		}
+	}
+	return losses
+}
+
+// run simulates the given number of iterations, passing each outcome to observe.
+func (s *simulation) run(iterations int, observe func(iteration int, eventsOccurred map[int]bool, eventImpacts map[int]map[string]float64)) {
+	for i := 0; i < iterations; i++ {
+		eventsOccurred, eventImpacts := s.iterate(i)
+		observe(i, eventsOccurred, eventImpacts)
+	}
+}
+
+// disableControls switches off every event marked as a control.
+func (s *simulation) disableControls() {
+	for _, event := range s.events {
+		if event.Control {
+			s.forced[event.ID] = false
+		}
+	}
+}
+
+// uniform returns the uniform [0, 1) draw for an iteration, event and stream.
+func (s *simulation) uniform(iteration, eventID, stream int) float64 {
+	return s.uniformAt(iteration, nil, eventID, stream)
+}
+
+// uniformAt is uniform within the episode of an iteration identified by its occurrence path.
+func (s *simulation) uniformAt(iteration int, path []int, eventID, stream int) float64 {
+	return float64(s.key(iteration, path, eventID, stream)>>11) / (1 << 53)
+}
+
+// rng returns a generator seeded for an iteration, event and stream.
+func (s *simulation) rng(iteration, eventID, stream int) *rand.Rand {
+	return s.rngAt(iteration, nil, eventID, stream)
+}
+
+// rngAt is rng within the episode of an iteration identified by its occurrence path.
+func (s *simulation) rngAt(iteration int, path []int, eventID, stream int) *rand.Rand {
+	return rand.New(rand.NewSource(s.key(iteration, path, eventID, stream)))
+}
+
+// key hashes an iteration, occurrence path, event and stream into a seed. The top-level episode
+// has an empty path, so events that occur at most once keep their draws when frequencies are added.
+func (s *simulation) key(iteration int, path []int, eventID, stream int) uint64 {
+	h := splitmix64(s.seed)
+	h = splitmix64(h ^ uint64(int64(iteration)))
+	h = splitmix64(h ^ uint64(int64(eventID)))
+	h = splitmix64(h ^ uint64(int64(stream)))
+	for _, k := range path {
+		h = splitmix64(h ^ uint64(int64(k)))
+	}
+	return h
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// totalImpacts sums the impacts of every event in an iteration per impact unit.
+func totalImpacts(eventImpacts map[int]map[string]float64) map[string]float64 {
+	totals := make(map[string]float64)
+	for _, impacts := range eventImpacts {
+		for unit, value := range impacts {
+			totals[unit] += value
+		}
+	}
+	return totals
+}
@@ -0,0 +1,107 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// testEvent is an event with a fixed yearly probability and a unit impact in USD.
+func testEvent(id int, name string, p float64, dependencies ...*risk.Dependency) *risk.Event {
+	return &risk.Event{
+		ID:   id,
+		Name: name,
+		Probability: &risk.Probability{
+			ExpectedFrequency: "yearly",
+			Minimum:           p,
+			MinimumConfidence: 0.9,
+			Maximum:           p,
+			MaximumConfidence: 0.9,
+		},
+		Impact: []*risk.Impact{{
+			ImpactID:                              1,
+			Name:                                  name,
+			Unit:                                  "USD",
+			MinimumIndividualUnitImpact:           100,
+			MinimumIndividualUnitImpactConfidence: 0.9,
+			MaximumIndividualUnitImpact:           1000,
+			MaximumIndividualUnitImpactConfidence: 0.9,
+			MinimumImpactEvents:                   1,
+			MinimumImpactEventsConfidence:         0.9,
+			MaximumImpactEvents:                   1,
+			MaximumImpactEventsConfidence:         0.9,
+			ExpectedFrequency:                     "yearly",
+		}},
+		Dependencies: dependencies,
+	}
+}
+
+// testModel is a threat (1) that causes a loss (3) unless a control (2) stops it.
+func testModel() []*risk.Event {
+	control := testEvent(2, "Control", 0.4)
+	control.Control = true
+	control.Impact = nil
+	return []*risk.Event{
+		testEvent(1, "Threat", 0.5),
+		control,
+		testEvent(3, "Loss", 0.6,
+			&risk.Dependency{DependsOnEventID: 1, Happens: true},
+			&risk.Dependency{DependsOnEventID: 2, Happens: false}),
+	}
+}
+
+const testSeed = 42
+
+func TestMonteCarloSeededIsReproducible(t *testing.T) {
+	probabilities1, impacts1, err := MonteCarloSeeded(testModel(), 2000, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probabilities2, impacts2, err := MonteCarloSeeded(testModel(), 2000, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(probabilities1, probabilities2) || !reflect.DeepEqual(impacts1, impacts2) {
+		t.Errorf("same seed gave %v %v and %v %v", probabilities1, impacts1, probabilities2, impacts2)
+	}
+
+	probabilities3, _, err := MonteCarloSeeded(testModel(), 2000, testSeed+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(probabilities1, probabilities3) {
+		t.Errorf("different seeds gave the same probabilities %v", probabilities1)
+	}
+}
+
+func TestSharedEventsSeeCommonRandomNumbers(t *testing.T) {
+	base, err := newSimulation(testModel(), testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Adding an unrelated event and switching off the control must not change the draws of the shared events.
+	extended := append(testModel(), testEvent(4, "Unrelated", 0.3))
+	variant, err := newSimulation(extended, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variant.disableControls()
+
+	for _, id := range []int{1, 3} {
+		if base.probabilities[id] != variant.probabilities[id] {
+			t.Errorf("event %d initial probability %f differs from %f", id, variant.probabilities[id], base.probabilities[id])
+		}
+	}
+	for i := 0; i < 2000; i++ {
+		baseOccurred, _ := base.iterate(i)
+		variantOccurred, _ := variant.iterate(i)
+		if baseOccurred[1] != variantOccurred[1] {
+			t.Fatalf("iteration %d: threat occurred %v in one model and %v in the other", i, baseOccurred[1], variantOccurred[1])
+		}
+		// Without the control the loss can only occur more often, never less.
+		if baseOccurred[3] && !variantOccurred[3] {
+			t.Fatalf("iteration %d: loss occurred with the control but not without it", i)
+		}
+	}
+}
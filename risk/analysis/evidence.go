@@ -0,0 +1,166 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Percentile is the value of a distribution at one percentile.
+type Percentile struct {
+	Percentile float64 `json:"Percentile"`
+	Value      float64 `json:"Value"`
+}
+
+// ImpactDistribution summarizes the distribution of an impact unit's yearly total.
+type ImpactDistribution struct {
+	Unit        string        `json:"Unit"`
+	Mean        float64       `json:"Mean"`
+	StdDev      float64       `json:"StdDev"`
+	Percentiles []*Percentile `json:"Percentiles"`
+}
+
+// EventPosterior is an event's probability given the evidence.
+type EventPosterior struct {
+	EventID     int     `json:"EventID"`
+	Name        string  `json:"Name"`
+	Probability float64 `json:"Probability"`
+}
+
+// Posterior is the result of conditioning the model on evidence.
+type Posterior struct {
+	Iterations int      `json:"Iterations"`
+	Evidence   Evidence `json:"Evidence"`
+	// EvidenceProbability is the estimated probability of observing the evidence in a year.
+	EvidenceProbability float64 `json:"EvidenceProbability"`
+	// EffectiveSampleSize is the number of unweighted iterations the weighted sample is worth.
+	EffectiveSampleSize float64               `json:"EffectiveSampleSize"`
+	Events              []*EventPosterior     `json:"Events"`
+	Impacts             []*ImpactDistribution `json:"Impacts"`
+}
+
+// Condition answers questions such as "given that an employee accepted a malicious Duo push, what is the
+// probability of a major ransomware event?". It simulates the model with likelihood weighting: observed
+// events are set to their observed outcome and each iteration is weighted by the likelihood of the
+// evidence, and it returns posterior event probabilities and impact distributions.
+func Condition(events []*risk.Event, evidence Evidence, iterations int) (*Posterior, error) {
+	return ConditionSeeded(events, evidence, iterations, time.Now().UnixNano())
+}
+
+// ConditionSeeded is Condition with a fixed seed.
+func ConditionSeeded(events []*risk.Event, evidence Evidence, iterations int, seed int64) (*Posterior, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	sim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, err
+	}
+	if err := sim.setEvidence(evidence); err != nil {
+		return nil, err
+	}
+
+	weights := make([]float64, iterations)
+	occurrences := make(map[int]float64)
+	impacts := make(map[string][]float64)
+	var totalWeight, totalSquaredWeight float64
+
+	for i := 0; i < iterations; i++ {
+		eventsOccurred, eventImpacts, weight := sim.iterateWeighted(i)
+		weights[i] = weight
+		totalWeight += weight
+		totalSquaredWeight += weight * weight
+
+		for eventID, happened := range eventsOccurred {
+			if happened {
+				occurrences[eventID] += weight
+			}
+		}
+		for unit, value := range totalImpacts(eventImpacts) {
+			if _, ok := impacts[unit]; !ok {
+				impacts[unit] = make([]float64, iterations)
+			}
+			impacts[unit][i] = value
+		}
+	}
+
+	if totalWeight == 0 {
+		return nil, fmt.Errorf("the evidence was impossible in every iteration")
+	}
+
+	posterior := &Posterior{
+		Iterations:          iterations,
+		Evidence:            evidence,
+		EvidenceProbability: totalWeight / float64(iterations),
+		EffectiveSampleSize: totalWeight * totalWeight / totalSquaredWeight,
+	}
+	for _, event := range events {
+		posterior.Events = append(posterior.Events, &EventPosterior{
+			EventID:     event.ID,
+			Name:        event.Name,
+			Probability: occurrences[event.ID] / totalWeight,
+		})
+	}
+
+	units := make(map[string]bool)
+	for unit := range impacts {
+		units[unit] = true
+	}
+	for _, unit := range sortedKeys(units) {
+		posterior.Impacts = append(posterior.Impacts, weightedDistribution(unit, impacts[unit], weights))
+	}
+
+	return posterior, nil
+}
+
+// weightedDistribution summarizes weighted values with their mean, standard deviation and reportedPercentiles.
+func weightedDistribution(unit string, values, weights []float64) *ImpactDistribution {
+	x := append([]float64(nil), values...)
+	w := append([]float64(nil), weights...)
+	sort.Sort(byValue{x, w})
+
+	// Likelihood weights are not frequencies, so the variance is normalized by the total weight.
+	var sum, total float64
+	for i := range x {
+		sum += w[i] * x[i]
+		total += w[i]
+	}
+	distribution := &ImpactDistribution{Unit: unit, Mean: sum / total}
+	var squares float64
+	for i := range x {
+		squares += w[i] * (x[i] - distribution.Mean) * (x[i] - distribution.Mean)
+	}
+	distribution.StdDev = math.Sqrt(squares / total)
+	for _, q := range reportedPercentiles {
+		distribution.Percentiles = append(distribution.Percentiles, &Percentile{
+			Percentile: q,
+			Value:      stat.Quantile(q, stat.Empirical, x, w),
+		})
+	}
+	return distribution
+}
+
+// distribution summarizes unweighted values with their mean, standard deviation and reportedPercentiles.
+func distribution(unit string, values []float64) *ImpactDistribution {
+	weights := make([]float64, len(values))
+	for i := range weights {
+		weights[i] = 1
+	}
+	return weightedDistribution(unit, values, weights)
+}
+
+// byValue sorts values and their weights together by value.
+type byValue struct {
+	values, weights []float64
+}
+
+func (b byValue) Len() int           { return len(b.values) }
+func (b byValue) Less(i, j int) bool { return b.values[i] < b.values[j] }
+func (b byValue) Swap(i, j int) {
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+	b.weights[i], b.weights[j] = b.weights[j], b.weights[i]
+}
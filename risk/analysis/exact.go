@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// MaxExactStates is the largest number of partial event assignments exact enumeration visits before giving up.
+const MaxExactStates = 1 << 22
+
+// ExactProbabilities computes each event's yearly probability exactly, without sampling noise, by enumerating
+// every combination of event outcomes the simulation can produce, weighted by its probability.
+// It follows the same rules as MonteCarlo (events in order, adjusted for their dependencies with
+// UpdateEventProbabilityWithDependency), so for the same seed it returns the values MonteCarlo converges to.
+// Enumeration skips impossible branches and fails once more than MaxExactStates assignments are visited.
+func ExactProbabilities(events []*risk.Event) (map[int]float64, error) {
+	return ExactProbabilitiesSeeded(events, time.Now().UnixNano())
+}
+
+// ExactProbabilitiesSeeded is ExactProbabilities with a fixed seed for the initial probability estimates.
+func ExactProbabilitiesSeeded(events []*risk.Event, seed int64) (map[int]float64, error) {
+	sim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, err
+	}
+	return sim.exact()
+}
+
+// exact enumerates the simulation's outcomes and returns each event's marginal probability.
+func (s *simulation) exact() (map[int]float64, error) {
+	if err := s.singleOccurrence(); err != nil {
+		return nil, err
+	}
+	marginals := make(map[int]float64, len(s.events))
+	for _, event := range s.events {
+		marginals[event.ID] = 0
+	}
+	probabilities, _ := s.estimates(wholeYear)
+
+	states := 0
+	eventsOccurred := make(map[int]bool, len(s.events))
+	var enumerate func(i int, mass float64) error
+	enumerate = func(i int, mass float64) error {
+		if states++; states > MaxExactStates {
+			return fmt.Errorf("more than %d states, the model is too large for exact enumeration", MaxExactStates)
+		}
+		if i == len(s.events) {
+			for eventID, happened := range eventsOccurred {
+				if happened {
+					marginals[eventID] += mass
+				}
+			}
+			return nil
+		}
+
+		event := s.events[i]
+		p := UpdateEventProbabilityWithDependency(event, eventsOccurred, probabilities)
+		if happened, ok := s.forced[event.ID]; ok {
+			p = 0
+			if happened {
+				p = 1
+			}
+		}
+
+		for _, branch := range []struct {
+			happened bool
+			mass     float64
+		}{{true, mass * p}, {false, mass * (1 - p)}} {
+			if branch.mass == 0 {
+				continue
+			}
+			eventsOccurred[event.ID] = branch.happened
+			if err := enumerate(i+1, branch.mass); err != nil {
+				return err
+			}
+		}
+		delete(eventsOccurred, event.ID)
+		return nil
+	}
+
+	if err := enumerate(0, 1); err != nil {
+		return nil, err
+	}
+	return marginals, nil
+}
@@ -0,0 +1,36 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExactMethodsAgreeWithSimulation(t *testing.T) {
+	const iterations = 100000
+	events := testModel()
+
+	exact, err := ExactProbabilitiesSeeded(events, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eliminated, err := VariableElimination{Seed: testSeed}.Marginals(events, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simulated, _, err := MonteCarloSeeded(events, iterations, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range events {
+		p := exact[event.ID]
+		if math.Abs(eliminated[event.ID]-p) > 1e-12 {
+			t.Errorf("event %d: variable elimination gave %f, enumeration %f", event.ID, eliminated[event.ID], p)
+		}
+		// Allow four standard errors of the simulated frequency.
+		tolerance := 4 * math.Sqrt(p*(1-p)/iterations)
+		if math.Abs(simulated[event.ID]-p) > tolerance {
+			t.Errorf("event %d: simulation gave %f, exact %f (tolerance %f)", event.ID, simulated[event.ID], p, tolerance)
+		}
+	}
+}
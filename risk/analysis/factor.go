@@ -0,0 +1,85 @@
+package analysis
+
+// factor is a table over binary event variables. Bit j of a table index is the outcome of vars[j].
+type factor struct {
+	vars  []int
+	table []float64
+}
+
+func (f *factor) position(eventID int) int {
+	for j, v := range f.vars {
+		if v == eventID {
+			return j
+		}
+	}
+	return -1
+}
+
+// multiply returns the product of two factors over the union of their variables.
+func multiply(f, g *factor) *factor {
+	vars := append([]int(nil), f.vars...)
+	for _, v := range g.vars {
+		if f.position(v) < 0 {
+			vars = append(vars, v)
+		}
+	}
+	result := &factor{vars: vars, table: make([]float64, 1<<len(vars))}
+
+	fBits := make([]int, len(f.vars))
+	for j, v := range f.vars {
+		fBits[j] = result.position(v)
+	}
+	gBits := make([]int, len(g.vars))
+	for j, v := range g.vars {
+		gBits[j] = result.position(v)
+	}
+
+	for index := range result.table {
+		result.table[index] = f.table[project(index, fBits)] * g.table[project(index, gBits)]
+	}
+	return result
+}
+
+// sumOut returns the factor with the variable summed out.
+func sumOut(f *factor, eventID int) *factor {
+	bit := f.position(eventID)
+	if bit < 0 {
+		return f
+	}
+	vars := append(append([]int(nil), f.vars[:bit]...), f.vars[bit+1:]...)
+	result := &factor{vars: vars, table: make([]float64, 1<<len(vars))}
+	for index, value := range f.table {
+		result.table[removeBit(index, bit)] += value
+	}
+	return result
+}
+
+// reduce returns the factor restricted to the variable having the given outcome.
+func reduce(f *factor, eventID int, happened bool) *factor {
+	bit := f.position(eventID)
+	if bit < 0 {
+		return f
+	}
+	vars := append(append([]int(nil), f.vars[:bit]...), f.vars[bit+1:]...)
+	result := &factor{vars: vars, table: make([]float64, 1<<len(vars))}
+	for index, value := range f.table {
+		if (index>>bit&1 == 1) == happened {
+			result.table[removeBit(index, bit)] = value
+		}
+	}
+	return result
+}
+
+// project maps an index of a larger factor onto a smaller one whose bit j is bit bits[j] of the larger.
+func project(index int, bits []int) int {
+	projected := 0
+	for j, bit := range bits {
+		projected |= (index >> bit & 1) << j
+	}
+	return projected
+}
+
+func removeBit(index, bit int) int {
+	low := index & (1<<bit - 1)
+	return (index>>(bit+1))<<bit | low
+}
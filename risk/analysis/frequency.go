@@ -0,0 +1,255 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/utils"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// OccurrenceDistribution summarizes how many times an event occurs in a simulated year.
+type OccurrenceDistribution struct {
+	EventID int    `json:"EventID"`
+	Name    string `json:"Name"`
+	// Probability is the share of years in which the event occurred at least once.
+	Probability float64       `json:"Probability"`
+	Mean        float64       `json:"Mean"`
+	StdDev      float64       `json:"StdDev"`
+	Percentiles []*Percentile `json:"Percentiles"`
+}
+
+// Occurrences simulates the model and reports the distribution of each event's yearly number of occurrences.
+// Events with a Frequency can occur many times a year. With PropagateEpisodes every occurrence gives the events
+// that depend on it their own chance to occur, and other events occur at most once per occurrence of what they
+// depend on; with PropagateThinning counts are thinned down the tree. An empty propagation uses PropagateEpisodes.
+func Occurrences(events []*risk.Event, propagation Propagation, iterations int) ([]*OccurrenceDistribution, error) {
+	return OccurrencesSeeded(events, propagation, iterations, time.Now().UnixNano())
+}
+
+// OccurrencesSeeded is Occurrences with a fixed seed.
+func OccurrencesSeeded(events []*risk.Event, propagation Propagation, iterations int, seed int64) ([]*OccurrenceDistribution, error) {
+	occurrences, _, err := YearlyOutcomesSeeded(events, propagation, iterations, seed)
+	return occurrences, err
+}
+
+// YearlyImpact is the distribution of an impact unit's yearly total, such as the annualized loss exposure,
+// with its primary and secondary losses reported separately.
+type YearlyImpact struct {
+	Unit      string              `json:"Unit"`
+	Total     *ImpactDistribution `json:"Total"`
+	Primary   *ImpactDistribution `json:"Primary"`
+	Secondary *ImpactDistribution `json:"Secondary"`
+}
+
+// YearlyImpacts simulates the model and reports the distribution of each impact unit's yearly total.
+// Unlike MonteCarlo, which averages impacts over the iterations in which they occur, the mean is taken over
+// every simulated year, and every occurrence of an event adds its impacts. Counts propagate as in Occurrences.
+func YearlyImpacts(events []*risk.Event, propagation Propagation, iterations int) ([]*YearlyImpact, error) {
+	return YearlyImpactsSeeded(events, propagation, iterations, time.Now().UnixNano())
+}
+
+// YearlyImpactsSeeded is YearlyImpacts with a fixed seed.
+func YearlyImpactsSeeded(events []*risk.Event, propagation Propagation, iterations int, seed int64) ([]*YearlyImpact, error) {
+	_, impacts, err := YearlyOutcomesSeeded(events, propagation, iterations, seed)
+	return impacts, err
+}
+
+// YearlyOutcomes reports the results of Occurrences and YearlyImpacts from a single simulation of the model.
+func YearlyOutcomes(events []*risk.Event, propagation Propagation, iterations int) ([]*OccurrenceDistribution, []*YearlyImpact, error) {
+	return YearlyOutcomesSeeded(events, propagation, iterations, time.Now().UnixNano())
+}
+
+// YearlyOutcomesSeeded is YearlyOutcomes with a fixed seed; its results match those of OccurrencesSeeded and
+// YearlyImpactsSeeded with the same seed.
+func YearlyOutcomesSeeded(events []*risk.Event, propagation Propagation, iterations int, seed int64) ([]*OccurrenceDistribution, []*YearlyImpact, error) {
+	if iterations < 1 {
+		return nil, nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	sim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := sim.setPropagation(propagation); err != nil {
+		return nil, nil, err
+	}
+
+	counts, totals, secondary := yearlyOutcomes(sim, iterations)
+
+	occurrences := make([]*OccurrenceDistribution, 0, len(events))
+	for _, event := range events {
+		x := counts[event.ID]
+		sort.Float64s(x)
+		distribution := &OccurrenceDistribution{EventID: event.ID, Name: event.Name}
+		distribution.Mean, distribution.StdDev = stat.MeanStdDev(x, nil)
+		distribution.Probability = 1 - float64(sort.SearchFloat64s(x, 1))/float64(iterations)
+		for _, q := range reportedPercentiles {
+			distribution.Percentiles = append(distribution.Percentiles, &Percentile{
+				Percentile: q,
+				Value:      stat.Quantile(q, stat.Empirical, x, nil),
+			})
+		}
+		occurrences = append(occurrences, distribution)
+	}
+
+	units := make(map[string]bool)
+	for unit := range totals {
+		units[unit] = true
+	}
+	impacts := make([]*YearlyImpact, 0, len(units))
+	for _, unit := range sortedKeys(units) {
+		impacts = append(impacts, yearlyImpact(unit, totals[unit], secondary[unit]))
+	}
+	return occurrences, impacts, nil
+}
+
+// yearlyOutcomes simulates the iterations and returns each event's number of occurrences and each unit's total
+// impact and secondary loss per iteration.
+func yearlyOutcomes(sim *simulation, iterations int) (map[int][]float64, map[string][]float64, map[string][]float64) {
+	counts := make(map[int][]float64, len(sim.events))
+	for _, event := range sim.events {
+		counts[event.ID] = make([]float64, iterations)
+	}
+	totals := make(map[string][]float64)
+	secondary := make(map[string][]float64)
+	record := func(values map[string][]float64, i int, impacts map[string]float64) {
+		for unit, value := range impacts {
+			if _, ok := values[unit]; !ok {
+				values[unit] = make([]float64, iterations)
+			}
+			values[unit][i] = value
+		}
+	}
+	for i := 0; i < iterations; i++ {
+		o := sim.iterateOutcome(i)
+		for eventID, count := range o.counts {
+			counts[eventID][i] = float64(count)
+		}
+		record(totals, i, totalImpacts(o.impacts))
+		record(secondary, i, totalImpacts(o.secondary))
+	}
+	return counts, totals, secondary
+}
+
+// yearlyImpact summarizes a unit's yearly totals and their secondary part; a nil secondary is all zeros.
+func yearlyImpact(unit string, totals, secondary []float64) *YearlyImpact {
+	if secondary == nil {
+		secondary = make([]float64, len(totals))
+	}
+	primary := make([]float64, len(totals))
+	for i := range totals {
+		primary[i] = totals[i] - secondary[i]
+	}
+	return &YearlyImpact{
+		Unit:      unit,
+		Total:     distribution(unit, totals),
+		Primary:   distribution(unit, primary),
+		Secondary: distribution(unit, secondary),
+	}
+}
+
+// yearlyRate estimates a frequency event's expected yearly number of occurrences and returns it with the
+// probability of at least one occurrence in a year.
+func yearlyRate(event *risk.Event) (float64, float64, error) {
+	frequency := event.Frequency
+	if frequency.Minimum < 0 || frequency.Maximum < frequency.Minimum {
+		return 0, 0, fmt.Errorf("event %d (%s) frequency must satisfy 0 <= minimum <= maximum", event.ID, event.Name)
+	}
+	scaledMin, err := utils.AdjustRateForTime(frequency.Minimum, frequency.ExpectedFrequency)
+	if err != nil {
+		return 0, 0, fmt.Errorf("event %d (%s) frequency: %w", event.ID, event.Name, err)
+	}
+	scaledMax, _ := utils.AdjustRateForTime(frequency.Maximum, frequency.ExpectedFrequency)
+	rate := weightedAverageWithConfidence(scaledMin, scaledMax, frequency.MinimumConfidence, frequency.MaximumConfidence)
+
+	switch frequency.Distribution {
+	case risk.DistributionPoisson, "":
+	case risk.DistributionNegativeBinomial:
+		if frequency.Dispersion <= 0 {
+			return 0, 0, fmt.Errorf("event %d (%s) negative binomial frequency needs a positive dispersion", event.ID, event.Name)
+		}
+	default:
+		return 0, 0, fmt.Errorf("event %d (%s) has unknown count distribution %q", event.ID, event.Name, frequency.Distribution)
+	}
+	return rate, atLeastOnce(frequency, rate), nil
+}
+
+// atLeastOnce is the probability that a count with the frequency's distribution and the given mean is positive.
+func atLeastOnce(frequency *risk.Frequency, rate float64) float64 {
+	if frequency.Distribution == risk.DistributionNegativeBinomial {
+		r := frequency.Dispersion
+		return 1 - math.Pow(r/(r+rate), r)
+	}
+	return 1 - math.Exp(-rate)
+}
+
+// count draws how many times a frequency event occurs in an episode. p is its probability adjusted for its
+// dependencies, which scales its rate over the simulated period.
+func (s *simulation) count(iteration int, path []int, event *risk.Event, p float64, o *outcome) int {
+	rate := o.rates[event.ID]
+	if base := o.probabilities[event.ID]; base > 0 {
+		rate *= p / base
+	}
+	if rate <= 0 {
+		return 0
+	}
+
+	rng := s.rngAt(iteration, path, event.ID, streamOccurrence)
+	if event.Frequency.Distribution == risk.DistributionNegativeBinomial {
+		// A negative binomial count is a Poisson count whose rate is gamma distributed.
+		r := event.Frequency.Dispersion
+		rate = distuv.Gamma{Alpha: r, Beta: r / rate, Src: rng}.Rand()
+	}
+	return int(distuv.Poisson{Lambda: rate, Src: rng}.Rand())
+}
+
+// subtree lists, in model order, the events that depend directly or indirectly on the given event.
+func subtree(events []*risk.Event, eventID int) []*risk.Event {
+	inside := map[int]bool{eventID: true}
+	var descendants []*risk.Event
+	for _, event := range events {
+		for _, dependency := range event.Dependencies {
+			if inside[dependency.DependsOnEventID] && !inside[event.ID] {
+				inside[event.ID] = true
+				descendants = append(descendants, event)
+			}
+		}
+	}
+	return descendants
+}
+
+// setEvidence conditions the simulation on the evidence. Evidence is a yearly outcome, so it cannot be
+// placed on frequency events or on the events simulated once per occurrence of one.
+func (s *simulation) setEvidence(evidence Evidence) error {
+	for eventID := range evidence {
+		if _, ok := s.probabilities[eventID]; !ok {
+			return fmt.Errorf("evidence on unknown event %d", eventID)
+		}
+		if _, ok := s.rates[eventID]; ok {
+			return fmt.Errorf("evidence on event %d, which can occur several times a year", eventID)
+		}
+		for frequencyEventID, descendants := range s.subtrees {
+			for _, descendant := range descendants {
+				if descendant.ID == eventID {
+					return fmt.Errorf("evidence on event %d, which is simulated once per occurrence of event %d", eventID, frequencyEventID)
+				}
+			}
+		}
+	}
+	s.evidence = evidence
+	return nil
+}
+
+// singleOccurrence returns an error if any event has a Frequency, which exact methods cannot enumerate.
+func (s *simulation) singleOccurrence() error {
+	for _, event := range s.events {
+		if event.Frequency != nil {
+			return fmt.Errorf("event %d (%s) can occur several times a year, which is only supported by simulation", event.ID, event.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/utils"
+	"gonum.org/v1/gonum/stat"
+)
+
+// GoalSeekMaxSteps is the largest number of bisection steps a goal seek performs.
+const GoalSeekMaxSteps = 50
+
+// Output selects a result of the simulation that an analysis measures.
+type Output struct {
+	// EventID is the event whose yearly probability is measured, unless Loss is set.
+	EventID int `json:"EventID,omitempty"`
+	// Loss measures a statistic of the yearly loss distribution instead of an event probability.
+	Loss *LossMeasure `json:"Loss,omitempty"`
+}
+
+// Goal is the output value a goal seek tries to hit.
+type Goal struct {
+	Output
+	Value float64 `json:"Value"`
+}
+
+// GoalSeekResult is the parameter value found by a goal seek.
+type GoalSeekResult struct {
+	Input Input   `json:"Input"`
+	Value float64 `json:"Value"`
+	// Precision is half the width of the final bisection interval around Value.
+	Precision float64 `json:"Precision"`
+	Target    float64 `json:"Target"`
+	Achieved  float64 `json:"Achieved"`
+	// AchievedStandardError is the Monte Carlo standard error of Achieved. It is zero for loss percentiles.
+	AchievedStandardError float64 `json:"AchievedStandardError"`
+	Steps                 int     `json:"Steps"`
+}
+
+// GoalSeek varies one input of the model, a parameter of an event or of one of its impacts, between low and high
+// by bisection, simulating the model with common random numbers at each step, and returns the value at which
+// the goal is hit.
+// The goal's output must move monotonically with the parameter and be bracketed by low and high.
+// Bisection stops when the interval is narrower than tolerance.
+func GoalSeek(events []*risk.Event, input Input, low, high float64, goal Goal, tolerance float64, iterations int) (*GoalSeekResult, error) {
+	return GoalSeekSeeded(events, input, low, high, goal, tolerance, iterations, time.Now().UnixNano())
+}
+
+// GoalSeekSeeded is GoalSeek with a fixed seed.
+func GoalSeekSeeded(events []*risk.Event, input Input, low, high float64, goal Goal, tolerance float64, iterations int, seed int64) (*GoalSeekResult, error) {
+	if iterations < 2 {
+		return nil, fmt.Errorf("at least 2 iterations are required, got %d", iterations)
+	}
+	if low > high {
+		low, high = high, low
+	}
+	if tolerance <= 0 {
+		return nil, fmt.Errorf("tolerance must be positive, got %f", tolerance)
+	}
+	if err := goal.Output.validate(events); err != nil {
+		return nil, err
+	}
+
+	evaluate := func(value float64) (float64, float64, error) {
+		modified, err := withInputs(events, map[Input]float64{input: value})
+		if err != nil {
+			return 0, 0, err
+		}
+		sim, err := newSimulation(modified, seed)
+		if err != nil {
+			return 0, 0, err
+		}
+		output, se := measureOutput(sim, iterations, goal.Output)
+		return output, se, nil
+	}
+
+	lowOutput, lowSE, err := evaluate(low)
+	if err != nil {
+		return nil, err
+	}
+	highOutput, highSE, err := evaluate(high)
+	if err != nil {
+		return nil, err
+	}
+	if (lowOutput-goal.Value)*(highOutput-goal.Value) > 0 {
+		return nil, fmt.Errorf("goal %f is not between %f at %f and %f at %f", goal.Value, lowOutput, low, highOutput, high)
+	}
+	increasing := highOutput >= lowOutput
+
+	result := &GoalSeekResult{Input: input, Target: goal.Value}
+	best, bestOutput, bestSE := low, lowOutput, lowSE
+	if math.Abs(highOutput-goal.Value) < math.Abs(lowOutput-goal.Value) {
+		best, bestOutput, bestSE = high, highOutput, highSE
+	}
+
+	for result.Steps < GoalSeekMaxSteps && high-low > tolerance {
+		mid := (low + high) / 2
+		output, se, err := evaluate(mid)
+		if err != nil {
+			return nil, err
+		}
+		result.Steps++
+
+		if math.Abs(output-goal.Value) <= math.Abs(bestOutput-goal.Value) {
+			best, bestOutput, bestSE = mid, output, se
+		}
+		if (output < goal.Value) == increasing {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	result.Value = best
+	result.Precision = (high - low) / 2
+	result.Achieved = bestOutput
+	result.AchievedStandardError = bestSE
+
+	return result, nil
+}
+
+// validate checks that the output's event exists and that its percentile is valid.
+func (o Output) validate(events []*risk.Event) error {
+	if o.Loss == nil && utils.FindEvent(o.EventID, events) == nil {
+		return fmt.Errorf("output event %d not found", o.EventID)
+	}
+	if o.Loss != nil && (o.Loss.Percentile < 0 || o.Loss.Percentile >= 1) {
+		return fmt.Errorf("percentile must be in [0, 1), got %f", o.Loss.Percentile)
+	}
+	return nil
+}
+
+// measureOutput simulates the model and returns the output with its Monte Carlo standard error.
+func measureOutput(sim *simulation, iterations int, output Output) (float64, float64) {
+	n := float64(iterations)
+
+	if output.Loss == nil {
+		var occurrences float64
+		sim.run(iterations, func(_ int, eventsOccurred map[int]bool, _ map[int]map[string]float64) {
+			if eventsOccurred[output.EventID] {
+				occurrences++
+			}
+		})
+		p := occurrences / n
+		return p, math.Sqrt(p * (1 - p) / n)
+	}
+
+	losses := make([]float64, iterations)
+	sim.run(iterations, func(i int, _ map[int]bool, eventImpacts map[int]map[string]float64) {
+		losses[i] = totalImpacts(eventImpacts)[output.Loss.Unit]
+	})
+	if output.Loss.Percentile == 0 {
+		mean, std := stat.MeanStdDev(losses, nil)
+		return mean, std / math.Sqrt(n)
+	}
+	sort.Float64s(losses)
+	return stat.Quantile(output.Loss.Percentile, stat.Empirical, losses, nil), 0
+}
@@ -0,0 +1,177 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/utils"
+)
+
+// HorizonYear is one simulated year of a multi-year horizon.
+type HorizonYear struct {
+	// Year counts from 1 for the first year of the horizon.
+	Year int `json:"Year"`
+	// EventProbabilities is the share of iterations in which each event occurred in this year.
+	EventProbabilities map[int]float64 `json:"EventProbabilities"`
+	// Loss is the distribution of the year's loss in the loss unit.
+	Loss *ImpactDistribution `json:"Loss"`
+	// CumulativeLoss is the distribution of the loss from the start of the horizon up to the end of this year.
+	CumulativeLoss *ImpactDistribution `json:"CumulativeLoss"`
+	// DiscountFactor converts a loss at the end of this year to its present value.
+	DiscountFactor float64 `json:"DiscountFactor"`
+}
+
+// HorizonEvent is an event's outlook over the whole horizon.
+type HorizonEvent struct {
+	EventID int    `json:"EventID"`
+	Name    string `json:"Name"`
+	// ProbabilityWithinHorizon is the share of iterations in which the event occurred in at least one year.
+	ProbabilityWithinHorizon float64 `json:"ProbabilityWithinHorizon"`
+	// ExpectedOccurrences is the mean number of times the event occurred over the horizon.
+	ExpectedOccurrences float64 `json:"ExpectedOccurrences"`
+}
+
+// Horizon is the result of a multi-year simulation.
+type Horizon struct {
+	Years        int             `json:"Years"`
+	Iterations   int             `json:"Iterations"`
+	DiscountRate float64         `json:"DiscountRate"`
+	LossUnit     string          `json:"LossUnit"`
+	PerYear      []*HorizonYear  `json:"PerYear"`
+	Events       []*HorizonEvent `json:"Events"`
+	// NPVLoss is the distribution of the present value of the losses over the horizon.
+	NPVLoss *ImpactDistribution `json:"NPVLoss"`
+	// NPVControlCosts is the present value of the yearly cost of the controls over the horizon.
+	NPVControlCosts float64 `json:"NPVControlCosts"`
+	// NPVTotal is the distribution of the present value of the losses and the control costs.
+	NPVTotal *ImpactDistribution `json:"NPVTotal"`
+}
+
+// MonteCarloHorizon simulates every iteration as a horizon of consecutive years, reporting each year's
+// occurrence probabilities and losses, the cumulative loss up to each year, the probability of each event
+// occurring at least once within the horizon, and the net present value of the losses in lossUnit.
+// The controls are the Control events in place, whose yearly Cost is paid over the horizon; every other
+// Control event of the model is switched off, so horizons with different controls can be compared.
+// Losses and the yearly Cost of the controls, in lossUnit, are discounted at discountRate from the end of the
+// year in which they occur. The years are independent draws of the model, so a one-year horizon with every
+// control of the model reproduces the yearly totals of YearlyImpacts with the same seed.
+func MonteCarloHorizon(events []*risk.Event, years int, discountRate float64, lossUnit string, controls []*CandidateControl, iterations int) (*Horizon, error) {
+	return MonteCarloHorizonSeeded(events, years, discountRate, lossUnit, controls, iterations, time.Now().UnixNano())
+}
+
+// MonteCarloHorizonSeeded is MonteCarloHorizon with a fixed seed.
+func MonteCarloHorizonSeeded(events []*risk.Event, years int, discountRate float64, lossUnit string, controls []*CandidateControl, iterations int, seed int64) (*Horizon, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	if years < 1 {
+		return nil, fmt.Errorf("the horizon must be at least 1 year, got %d", years)
+	}
+	if discountRate <= -1 {
+		return nil, fmt.Errorf("discount rate must be greater than -1, got %f", discountRate)
+	}
+	sim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, err
+	}
+	var yearlyCost float64
+	bought := make(map[int]bool, len(controls))
+	for _, control := range controls {
+		event := utils.FindEvent(control.EventID, events)
+		if event == nil {
+			return nil, fmt.Errorf("control %d is not an event in the model", control.EventID)
+		}
+		if !event.Control {
+			return nil, fmt.Errorf("event %d (%s) is not a control", event.ID, event.Name)
+		}
+		if bought[event.ID] {
+			return nil, fmt.Errorf("control %d (%s) is listed more than once", event.ID, event.Name)
+		}
+		if control.Cost < 0 {
+			return nil, fmt.Errorf("control %s has a negative cost", event.Name)
+		}
+		bought[event.ID] = true
+		yearlyCost += control.Cost
+	}
+	for _, event := range events {
+		if event.Control && !bought[event.ID] {
+			sim.forced[event.ID] = false
+		}
+	}
+
+	result := &Horizon{Years: years, Iterations: iterations, DiscountRate: discountRate, LossUnit: lossUnit}
+	losses := make([][]float64, years)
+	cumulative := make([][]float64, years)
+	occurred := make([]map[int]int, years)
+	for y := range losses {
+		losses[y] = make([]float64, iterations)
+		cumulative[y] = make([]float64, iterations)
+		occurred[y] = make(map[int]int)
+	}
+	npv := make([]float64, iterations)
+	within := make(map[int]int)
+	occurrences := make(map[int]int)
+	factors := make([]float64, years)
+	for y := range factors {
+		factors[y] = math.Pow(1+discountRate, -float64(y+1))
+		result.NPVControlCosts += yearlyCost * factors[y]
+	}
+
+	for i := 0; i < iterations; i++ {
+		seen := make(map[int]bool)
+		var total float64
+		for y := 0; y < years; y++ {
+			// Each year is its own engine iteration, numbered i*years+y so that the years of every iteration draw
+			// distinct random numbers and a one-year horizon uses the draws of iteration i. The trends of the
+			// events scale their estimates from one year to the next.
+			o := sim.iteratePeriod(i*years+y, period{year: y, length: 1})
+			loss := totalImpacts(o.impacts)[lossUnit]
+			losses[y][i] = loss
+			total += loss
+			cumulative[y][i] = total
+			npv[i] += loss * factors[y]
+			for eventID, count := range o.counts {
+				if count == 0 {
+					continue
+				}
+				occurred[y][eventID]++
+				occurrences[eventID] += count
+				if !seen[eventID] {
+					seen[eventID] = true
+					within[eventID]++
+				}
+			}
+		}
+	}
+
+	for y := 0; y < years; y++ {
+		year := &HorizonYear{
+			Year:               y + 1,
+			EventProbabilities: make(map[int]float64, len(events)),
+			Loss:               distribution(lossUnit, losses[y]),
+			CumulativeLoss:     distribution(lossUnit, cumulative[y]),
+			DiscountFactor:     factors[y],
+		}
+		for _, event := range events {
+			year.EventProbabilities[event.ID] = float64(occurred[y][event.ID]) / float64(iterations)
+		}
+		result.PerYear = append(result.PerYear, year)
+	}
+	for _, event := range events {
+		result.Events = append(result.Events, &HorizonEvent{
+			EventID:                  event.ID,
+			Name:                     event.Name,
+			ProbabilityWithinHorizon: float64(within[event.ID]) / float64(iterations),
+			ExpectedOccurrences:      float64(occurrences[event.ID]) / float64(iterations),
+		})
+	}
+	result.NPVLoss = distribution(lossUnit, npv)
+	totals := make([]float64, iterations)
+	for i := range npv {
+		totals[i] = npv[i] + result.NPVControlCosts
+	}
+	result.NPVTotal = distribution(lossUnit, totals)
+	return result, nil
+}
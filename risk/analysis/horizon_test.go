@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHorizonSwitchesOffControlsNotListed(t *testing.T) {
+	control := []*CandidateControl{{EventID: 2, Cost: 10}}
+	with, err := MonteCarloHorizonSeeded(testModel(), 3, 0.1, "USD", control, 5000, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	without, err := MonteCarloHorizonSeeded(testModel(), 3, 0.1, "USD", nil, 5000, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var costs float64
+	for y := 1; y <= 3; y++ {
+		costs += 10 / math.Pow(1.1, float64(y))
+	}
+	if math.Abs(with.NPVControlCosts-costs) > 1e-9 || without.NPVControlCosts != 0 {
+		t.Errorf("control costs %f and %f, want %f and 0", with.NPVControlCosts, without.NPVControlCosts, costs)
+	}
+	if without.PerYear[0].EventProbabilities[2] != 0 {
+		t.Errorf("the control occurred with probability %f although it was not listed", without.PerYear[0].EventProbabilities[2])
+	}
+	if with.NPVLoss.Mean >= without.NPVLoss.Mean {
+		t.Errorf("the control did not reduce the loss: %f with it, %f without", with.NPVLoss.Mean, without.NPVLoss.Mean)
+	}
+
+	// A one-year horizon with every control reproduces the yearly totals.
+	year, err := MonteCarloHorizonSeeded(testModel(), 1, 0, "USD", control, 5000, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yearly, err := YearlyImpactsSeeded(testModel(), PropagateEpisodes, 5000, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(year.PerYear[0].Loss.Mean-yearly[0].Total.Mean) > 1e-9 {
+		t.Errorf("one-year horizon loss %f, yearly impacts %f", year.PerYear[0].Loss.Mean, yearly[0].Total.Mean)
+	}
+}
+
+func TestHorizonRejectsInvalidControls(t *testing.T) {
+	for name, controls := range map[string][]*CandidateControl{
+		"not a control": {{EventID: 1, Cost: 10}},
+		"duplicate":     {{EventID: 2, Cost: 10}, {EventID: 2, Cost: 5}},
+		"negative cost": {{EventID: 2, Cost: -10}},
+		"missing":       {{EventID: 9, Cost: 10}},
+	} {
+		if _, err := MonteCarloHorizonSeeded(testModel(), 2, 0, "USD", controls, 10, testSeed); err == nil {
+			t.Errorf("%s: controls were accepted", name)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/utils"
+)
+
+// EventImportance holds the reliability importance measures of one event for a top event.
+// Ratios whose denominator is zero are reported as zero.
+type EventImportance struct {
+	EventID int    `json:"EventID"`
+	Name    string `json:"Name"`
+	// Probability is the event's simulated yearly probability.
+	Probability float64 `json:"Probability"`
+	// TopGivenOccurs and TopGivenNotOccurs are the top event's probability with the event forced to occur and to not occur.
+	TopGivenOccurs    float64 `json:"TopGivenOccurs"`
+	TopGivenNotOccurs float64 `json:"TopGivenNotOccurs"`
+	// Birnbaum is P(top | event occurs) - P(top | event does not occur). It is negative for controls,
+	// whose occurrence lowers the top event's probability.
+	Birnbaum float64 `json:"Birnbaum"`
+	// FussellVesely is (P(top) - P(top | event does not occur)) / P(top).
+	FussellVesely float64 `json:"FussellVesely"`
+	// RiskAchievementWorth is P(top | event occurs) / P(top).
+	RiskAchievementWorth float64 `json:"RiskAchievementWorth"`
+	// RiskReductionWorth is P(top) / P(top | event does not occur).
+	RiskReductionWorth float64 `json:"RiskReductionWorth"`
+}
+
+// Importance is the result of an importance analysis, ranked by the magnitude of Birnbaum importance.
+type Importance struct {
+	Iterations     int                `json:"Iterations"`
+	TopEvent       string             `json:"TopEvent"`
+	TopProbability float64            `json:"TopProbability"`
+	Events         []*EventImportance `json:"Events"`
+}
+
+// ImportanceMeasures computes Birnbaum, Fussell-Vesely, risk achievement worth and risk reduction worth
+// for every event other than the top event, by simulating the model with each event forced to occur and
+// forced not to occur, using common random numbers.
+func ImportanceMeasures(events []*risk.Event, topEventID int, iterations int) (*Importance, error) {
+	return ImportanceMeasuresSeeded(events, topEventID, iterations, time.Now().UnixNano())
+}
+
+// ImportanceMeasuresSeeded is ImportanceMeasures with a fixed seed.
+func ImportanceMeasuresSeeded(events []*risk.Event, topEventID int, iterations int, seed int64) (*Importance, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	topEvent := utils.FindEvent(topEventID, events)
+	if topEvent == nil {
+		return nil, fmt.Errorf("top event %d not found", topEventID)
+	}
+
+	evaluate := func(forced map[int]bool) (map[int]float64, error) {
+		sim, err := newSimulation(events, seed)
+		if err != nil {
+			return nil, err
+		}
+		for eventID, happens := range forced {
+			sim.forced[eventID] = happens
+		}
+		probabilities, _ := expectedOutcomes(sim, iterations)
+		return probabilities, nil
+	}
+
+	base, err := evaluate(nil)
+	if err != nil {
+		return nil, err
+	}
+	top := base[topEventID]
+	importance := &Importance{Iterations: iterations, TopEvent: topEvent.Name, TopProbability: top}
+
+	for _, event := range events {
+		if event.ID == topEventID {
+			continue
+		}
+		occurs, err := evaluate(map[int]bool{event.ID: true})
+		if err != nil {
+			return nil, err
+		}
+		notOccurs, err := evaluate(map[int]bool{event.ID: false})
+		if err != nil {
+			return nil, err
+		}
+
+		measures := &EventImportance{
+			EventID:           event.ID,
+			Name:              event.Name,
+			Probability:       base[event.ID],
+			TopGivenOccurs:    occurs[topEventID],
+			TopGivenNotOccurs: notOccurs[topEventID],
+		}
+		measures.Birnbaum = measures.TopGivenOccurs - measures.TopGivenNotOccurs
+		if top > 0 {
+			measures.FussellVesely = (top - measures.TopGivenNotOccurs) / top
+			measures.RiskAchievementWorth = measures.TopGivenOccurs / top
+		}
+		if measures.TopGivenNotOccurs > 0 {
+			measures.RiskReductionWorth = top / measures.TopGivenNotOccurs
+		}
+		importance.Events = append(importance.Events, measures)
+	}
+
+	sort.SliceStable(importance.Events, func(i, j int) bool {
+		return math.Abs(importance.Events[i].Birnbaum) > math.Abs(importance.Events[j].Birnbaum)
+	})
+
+	return importance, nil
+}
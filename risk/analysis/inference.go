@@ -0,0 +1,220 @@
+package analysis
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// MaxFactorVariables is the largest number of events a factor may span during variable elimination.
+const MaxFactorVariables = 24
+
+// Evidence maps event IDs to their observed outcomes.
+type Evidence map[int]bool
+
+// Engine computes each event's yearly probability, conditioned on evidence.
+// Sampling and VariableElimination let callers choose between simulation and exact inference.
+type Engine interface {
+	Marginals(events []*risk.Event, evidence Evidence) (map[int]float64, error)
+}
+
+// Sampling estimates marginals with the Monte Carlo simulation.
+type Sampling struct {
+	Iterations int
+	// Seed fixes the random numbers; zero uses the current time.
+	Seed int64
+}
+
+// Marginals runs MonteCarloSeeded, or ConditionSeeded when there is evidence, and returns its event probabilities.
+func (e Sampling) Marginals(events []*risk.Event, evidence Evidence) (map[int]float64, error) {
+	if len(evidence) == 0 {
+		probabilities, _, err := MonteCarloSeeded(events, e.Iterations, seedOrNow(e.Seed))
+		return probabilities, err
+	}
+
+	posterior, err := ConditionSeeded(events, evidence, e.Iterations, seedOrNow(e.Seed))
+	if err != nil {
+		return nil, err
+	}
+	probabilities := make(map[int]float64, len(posterior.Events))
+	for _, event := range posterior.Events {
+		probabilities[event.EventID] = event.Probability
+	}
+	return probabilities, nil
+}
+
+// VariableElimination computes exact marginals by treating the events as a Bayesian network.
+// Each event is a binary variable whose parents are its dependencies, with the conditional probabilities
+// the simulation uses (UpdateEventProbabilityWithDependency), so without evidence it agrees with
+// ExactProbabilities and with what MonteCarlo converges to for the same seed.
+type VariableElimination struct {
+	// Seed fixes the initial probability estimates; zero uses the current time.
+	Seed int64
+}
+
+// Marginals returns each event's probability given the evidence.
+func (e VariableElimination) Marginals(events []*risk.Event, evidence Evidence) (map[int]float64, error) {
+	sim, err := newSimulation(events, seedOrNow(e.Seed))
+	if err != nil {
+		return nil, err
+	}
+	return sim.eliminate(evidence)
+}
+
+func seedOrNow(seed int64) int64 {
+	if seed == 0 {
+		return time.Now().UnixNano()
+	}
+	return seed
+}
+
+// conditionalFactors builds each event's conditional probability table as a factor over the event and its parents.
+// Dependencies on events later in the order are treated as not occurred, as they are in the simulation.
+func (s *simulation) conditionalFactors() ([]*factor, error) {
+	position := make(map[int]int, len(s.events))
+	for i, event := range s.events {
+		position[event.ID] = i
+	}
+
+	probabilities, _ := s.estimates(wholeYear)
+	factors := make([]*factor, 0, len(s.events))
+	for i, event := range s.events {
+		var parents []int
+		seen := make(map[int]bool)
+		for _, dependency := range event.Dependencies {
+			id := dependency.DependsOnEventID
+			if p, ok := position[id]; ok && p < i && !seen[id] {
+				seen[id] = true
+				parents = append(parents, id)
+			}
+		}
+		if len(parents)+1 > MaxFactorVariables {
+			return nil, fmt.Errorf("event %d (%s) has too many dependencies for exact inference", event.ID, event.Name)
+		}
+
+		f := &factor{vars: append(parents, event.ID), table: make([]float64, 1<<(len(parents)+1))}
+		eventBit := len(parents)
+		for index := 0; index < 1<<len(parents); index++ {
+			assignment := make(map[int]bool, len(parents))
+			for j, parent := range parents {
+				assignment[parent] = index>>j&1 == 1
+			}
+			p := UpdateEventProbabilityWithDependency(event, assignment, probabilities)
+			if happened, ok := s.forced[event.ID]; ok {
+				p = 0
+				if happened {
+					p = 1
+				}
+			}
+			f.table[index|1<<eventBit] = p
+			f.table[index] = 1 - p
+		}
+		factors = append(factors, f)
+	}
+	return factors, nil
+}
+
+// eliminate computes every event's marginal probability given the evidence by variable elimination.
+func (s *simulation) eliminate(evidence Evidence) (map[int]float64, error) {
+	if err := s.singleOccurrence(); err != nil {
+		return nil, err
+	}
+	factors, err := s.conditionalFactors()
+	if err != nil {
+		return nil, err
+	}
+	for eventID, happened := range evidence {
+		if _, ok := s.probabilities[eventID]; !ok {
+			return nil, fmt.Errorf("evidence on unknown event %d", eventID)
+		}
+		for i, f := range factors {
+			factors[i] = reduce(f, eventID, happened)
+		}
+	}
+
+	marginals := make(map[int]float64, len(s.events))
+	for _, event := range s.events {
+		if happened, ok := evidence[event.ID]; ok {
+			marginals[event.ID] = 0
+			if happened {
+				marginals[event.ID] = 1
+			}
+			continue
+		}
+
+		query, err := eliminateAllBut(factors, event.ID)
+		if err != nil {
+			return nil, err
+		}
+		total := query.table[0] + query.table[1]
+		if total == 0 {
+			return nil, fmt.Errorf("the evidence is impossible under the model")
+		}
+		marginals[event.ID] = query.table[1] / total
+	}
+	return marginals, nil
+}
+
+// eliminateAllBut sums every variable other than keep out of the product of the factors,
+// choosing at each step the variable whose elimination creates the smallest factor.
+func eliminateAllBut(factors []*factor, keep int) (*factor, error) {
+	remaining := append([]*factor(nil), factors...)
+	for {
+		variables := make(map[int]bool)
+		for _, f := range remaining {
+			for _, v := range f.vars {
+				if v != keep {
+					variables[v] = true
+				}
+			}
+		}
+		if len(variables) == 0 {
+			break
+		}
+
+		best, bestSize := 0, -1
+		for v := range variables {
+			scope := make(map[int]bool)
+			for _, f := range remaining {
+				if f.position(v) >= 0 {
+					for _, u := range f.vars {
+						scope[u] = true
+					}
+				}
+			}
+			if bestSize < 0 || len(scope) < bestSize || (len(scope) == bestSize && v < best) {
+				best, bestSize = v, len(scope)
+			}
+		}
+		if bestSize > MaxFactorVariables {
+			return nil, fmt.Errorf("the model is too densely connected for exact inference")
+		}
+
+		var product *factor
+		var rest []*factor
+		for _, f := range remaining {
+			if f.position(best) < 0 {
+				rest = append(rest, f)
+			} else if product == nil {
+				product = f
+			} else {
+				product = multiply(product, f)
+			}
+		}
+		remaining = append(rest, sumOut(product, best))
+	}
+
+	result := &factor{vars: []int{keep}, table: []float64{1, 1}}
+	constant := 1.0
+	for _, f := range remaining {
+		if len(f.vars) == 0 {
+			constant *= f.table[0]
+			continue
+		}
+		result = multiply(result, f)
+	}
+	result.table[0] *= constant
+	result.table[1] *= constant
+	return result, nil
+}
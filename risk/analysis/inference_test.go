@@ -0,0 +1,25 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConditioningAgreesWithVariableElimination(t *testing.T) {
+	events := testModel()
+	evidence := Evidence{3: true}
+
+	exact, err := VariableElimination{Seed: testSeed}.Marginals(events, evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sampled, err := Sampling{Iterations: 100000, Seed: testSeed}.Marginals(events, evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range events {
+		if math.Abs(sampled[event.ID]-exact[event.ID]) > 0.01 {
+			t.Errorf("event %d given the loss: sampling gave %f, variable elimination %f", event.ID, sampled[event.ID], exact[event.ID])
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package analysis
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// EventRiskGap is an event's yearly probability with all controls disabled (inherent) and as modeled (residual).
+type EventRiskGap struct {
+	EventID             int     `json:"EventID"`
+	Name                string  `json:"Name"`
+	Control             bool    `json:"Control"`
+	InherentProbability float64 `json:"InherentProbability"`
+	ResidualProbability float64 `json:"ResidualProbability"`
+	Gap                 float64 `json:"Gap"`
+}
+
+// ImpactRiskGap is the expected yearly impact of a unit with all controls disabled (inherent) and as modeled (residual).
+type ImpactRiskGap struct {
+	Unit     string  `json:"Unit"`
+	Inherent float64 `json:"Inherent"`
+	Residual float64 `json:"Residual"`
+	Gap      float64 `json:"Gap"`
+}
+
+// InherentResidualRisk is the result of comparing a model with and without its controls.
+type InherentResidualRisk struct {
+	Iterations int              `json:"Iterations"`
+	Controls   []string         `json:"Controls"`
+	Events     []*EventRiskGap  `json:"Events"`
+	Impacts    []*ImpactRiskGap `json:"Impacts"`
+}
+
+// InherentResidual simulates the model twice with common random numbers: once with every event
+// marked as a control disabled (inherent risk) and once as modeled (residual risk).
+// Gaps are inherent minus residual, so a positive gap is the risk removed by the controls.
+func InherentResidual(events []*risk.Event, iterations int) (*InherentResidualRisk, error) {
+	return InherentResidualSeeded(events, iterations, time.Now().UnixNano())
+}
+
+// InherentResidualSeeded is InherentResidual with a fixed seed.
+func InherentResidualSeeded(events []*risk.Event, iterations int, seed int64) (*InherentResidualRisk, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+
+	result := &InherentResidualRisk{Iterations: iterations}
+	for _, event := range events {
+		if event != nil && event.Control {
+			result.Controls = append(result.Controls, event.Name)
+		}
+	}
+	if len(result.Controls) == 0 {
+		return nil, fmt.Errorf("no events are marked as controls")
+	}
+
+	inherentSim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, err
+	}
+	inherentSim.disableControls()
+	residualSim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	inherentProbabilities, inherentImpacts := expectedOutcomes(inherentSim, iterations)
+	residualProbabilities, residualImpacts := expectedOutcomes(residualSim, iterations)
+
+	for _, event := range events {
+		result.Events = append(result.Events, &EventRiskGap{
+			EventID:             event.ID,
+			Name:                event.Name,
+			Control:             event.Control,
+			InherentProbability: inherentProbabilities[event.ID],
+			ResidualProbability: residualProbabilities[event.ID],
+			Gap:                 inherentProbabilities[event.ID] - residualProbabilities[event.ID],
+		})
+	}
+
+	units := make(map[string]bool)
+	for unit := range inherentImpacts {
+		units[unit] = true
+	}
+	for unit := range residualImpacts {
+		units[unit] = true
+	}
+	for _, unit := range sortedKeys(units) {
+		result.Impacts = append(result.Impacts, &ImpactRiskGap{
+			Unit:     unit,
+			Inherent: inherentImpacts[unit],
+			Residual: residualImpacts[unit],
+			Gap:      inherentImpacts[unit] - residualImpacts[unit],
+		})
+	}
+
+	return result, nil
+}
+
+// expectedOutcomes runs a simulation and returns each event's occurrence probability
+// and each unit's expected impact per iteration.
+func expectedOutcomes(sim *simulation, iterations int) (map[int]float64, map[string]float64) {
+	probabilities := make(map[int]float64)
+	impacts := make(map[string]float64)
+
+	sim.run(iterations, func(_ int, eventsOccurred map[int]bool, eventImpacts map[int]map[string]float64) {
+		for eventID, happened := range eventsOccurred {
+			if happened {
+				probabilities[eventID]++
+			}
+		}
+		for unit, value := range totalImpacts(eventImpacts) {
+			impacts[unit] += value
+		}
+	})
+
+	for eventID := range probabilities {
+		probabilities[eventID] /= float64(iterations)
+	}
+	for unit := range impacts {
+		impacts[unit] /= float64(iterations)
+	}
+
+	return probabilities, impacts
+}
@@ -0,0 +1,186 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/statistics"
+)
+
+// UnitValue is the expected yearly monetary value of one impact unit.
+type UnitValue struct {
+	Unit string `json:"Unit"`
+	// Native is the unit's expected yearly total in its own unit.
+	Native float64 `json:"Native"`
+	// Value is Native converted to money.
+	Value float64 `json:"Value"`
+}
+
+// MonetaryImpacts is a model's yearly impacts in their native units together with their monetary total.
+type MonetaryImpacts struct {
+	Iterations int    `json:"Iterations"`
+	Currency   string `json:"Currency"`
+	// Native holds the yearly distribution of every impact unit in its own unit.
+	Native []*YearlyImpact `json:"Native"`
+	// Total is the yearly distribution of the sum of every monetized unit, in Currency.
+	Total *YearlyImpact `json:"Total"`
+	// Values lists the expected yearly value of each monetized unit, largest first.
+	Values []*UnitValue `json:"Values"`
+	// Unmonetized lists the units the registry cannot convert to money, which are left out of Total.
+	Unmonetized []string `json:"Unmonetized"`
+}
+
+// Monetize simulates the model and converts every impact unit to money with the registry, so that units such
+// as "Host Control Alert" can be added to dollar losses. Impacts already in currency count as they are.
+// When the registry declares units, impact units are normalized with NormalizeUnits first, and money in
+// other declared currencies, including the currency of a monetization, is converted to currency.
+// The factors of each monetization are drawn from their PERT distributions in every iteration, so the
+// uncertainty of the conversion is part of the total's distribution.
+func Monetize(events []*risk.Event, registry *risk.UnitRegistry, currency string, iterations int) (*MonetaryImpacts, error) {
+	return MonetizeSeeded(events, registry, currency, iterations, time.Now().UnixNano())
+}
+
+// MonetizeSeeded is Monetize with a fixed seed.
+func MonetizeSeeded(events []*risk.Event, registry *risk.UnitRegistry, currency string, iterations int, seed int64) (*MonetaryImpacts, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	if registry == nil {
+		registry = &risk.UnitRegistry{}
+	}
+	if len(registry.Units) > 0 {
+		var err error
+		if events, err = NormalizeUnits(events, registry); err != nil {
+			return nil, err
+		}
+		if currency, err = registry.Canonical(currency); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateRegistry(registry, currency); err != nil {
+		return nil, err
+	}
+	sim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	_, totals, secondary := yearlyOutcomes(sim, iterations)
+	units := make(map[string]bool)
+	for unit := range totals {
+		units[unit] = true
+	}
+
+	result := &MonetaryImpacts{Iterations: iterations, Currency: currency, Unmonetized: []string{}}
+	var monetized []string
+	// One unit is worth its rate in currency times its monetization factors.
+	rates := make(map[string]float64)
+	factors := make(map[string][]*risk.ValueFactor)
+	for _, unit := range sortedKeys(units) {
+		result.Native = append(result.Native, yearlyImpact(unit, totals[unit], secondary[unit]))
+		if rate, unitFactors, ok := unitValue(registry, unit, currency); ok {
+			monetized = append(monetized, unit)
+			rates[unit], factors[unit] = rate, unitFactors
+		} else {
+			result.Unmonetized = append(result.Unmonetized, unit)
+		}
+	}
+
+	money := make([]float64, iterations)
+	secondaryMoney := make([]float64, iterations)
+	for i := 0; i < iterations; i++ {
+		rng := sim.rng(i, 0, streamMonetization)
+		for _, unit := range monetized {
+			value := rates[unit]
+			for _, factor := range factors[unit] {
+				value *= statistics.GeneratePERTSampleFrom(rng, factor.Minimum, mostLikely(factor), factor.Maximum)
+			}
+			money[i] += totals[unit][i] * value
+			if secondary[unit] != nil {
+				secondaryMoney[i] += secondary[unit][i] * value
+			}
+		}
+	}
+	result.Total = yearlyImpact(currency, money, secondaryMoney)
+
+	for _, unit := range monetized {
+		value := &UnitValue{Unit: unit}
+		for i := 0; i < iterations; i++ {
+			value.Native += totals[unit][i]
+		}
+		value.Native /= float64(iterations)
+		// The factors are independent, so the expected value is the product of their PERT means.
+		value.Value = value.Native * rates[unit]
+		for _, factor := range factors[unit] {
+			value.Value *= (factor.Minimum + 4*mostLikely(factor) + factor.Maximum) / 6
+		}
+		result.Values = append(result.Values, value)
+	}
+	sort.SliceStable(result.Values, func(i, j int) bool { return result.Values[i].Value > result.Values[j].Value })
+
+	return result, nil
+}
+
+// unitValue returns the rate and factors that convert one unit to currency: money in another declared
+// currency is converted at its rate, and monetized units are worth the product of their factors, converted
+// from the currency of their monetization. It reports false for units that cannot be monetized.
+func unitValue(registry *risk.UnitRegistry, unit, currency string) (float64, []*risk.ValueFactor, bool) {
+	if unit == currency {
+		return 1, nil, true
+	}
+	if rate, err := registry.Convert(1, unit, currency); err == nil {
+		return rate, nil, true
+	}
+	monetization := registry.Monetization(unit)
+	if monetization == nil {
+		return 0, nil, false
+	}
+	rate := 1.0
+	if monetization.Currency != currency {
+		// validateRegistry has checked that the currencies convert.
+		rate, _ = registry.Convert(1, monetization.Currency, currency)
+	}
+	return rate, monetization.Factors, true
+}
+
+// validateRegistry checks that every monetization is in the currency, or in a currency the registry converts
+// to it, and has consistent factors.
+func validateRegistry(registry *risk.UnitRegistry, currency string) error {
+	if currency == "" {
+		return fmt.Errorf("no currency given")
+	}
+	if err := registry.Validate(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, monetization := range registry.Monetizations {
+		if monetization == nil {
+			return fmt.Errorf("nil monetization in unit registry")
+		}
+		if seen[monetization.Unit] {
+			return fmt.Errorf("unit %s is monetized twice", monetization.Unit)
+		}
+		seen[monetization.Unit] = true
+		if monetization.Currency != currency {
+			if _, err := registry.Convert(1, monetization.Currency, currency); err != nil {
+				return fmt.Errorf("unit %s is monetized in %s, not %s: %w", monetization.Unit, monetization.Currency, currency, err)
+			}
+		}
+		for _, factor := range monetization.Factors {
+			if factor.Minimum > factor.Maximum || (factor.MostLikely != 0 && (factor.MostLikely < factor.Minimum || factor.MostLikely > factor.Maximum)) {
+				return fmt.Errorf("unit %s factor %s must satisfy minimum <= most likely <= maximum", monetization.Unit, factor.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// mostLikely is the factor's most likely value, defaulting to the midpoint of its bounds.
+func mostLikely(factor *risk.ValueFactor) float64 {
+	if factor.MostLikely == 0 {
+		return (factor.Minimum + factor.Maximum) / 2
+	}
+	return factor.MostLikely
+}
@@ -0,0 +1,152 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// Parameter names an input of an event or of one of its impacts that an analysis may vary.
+type Parameter string
+
+const (
+	ProbabilityMinimum Parameter = "Probability.Minimum"
+	ProbabilityMaximum Parameter = "Probability.Maximum"
+	// ProbabilityRange sets both the minimum and the maximum probability to the same value.
+	ProbabilityRange Parameter = "Probability.Range"
+
+	FrequencyMinimum Parameter = "Frequency.Minimum"
+	FrequencyMaximum Parameter = "Frequency.Maximum"
+	// FrequencyRange sets both the minimum and the maximum expected number of occurrences to the same value.
+	FrequencyRange Parameter = "Frequency.Range"
+
+	ImpactUnitMinimum Parameter = "Impact.MinimumIndividualUnitImpact"
+	ImpactUnitMaximum Parameter = "Impact.MaximumIndividualUnitImpact"
+	// ImpactUnitRange sets both the minimum and the maximum individual unit impact to the same value.
+	ImpactUnitRange Parameter = "Impact.IndividualUnitImpactRange"
+
+	ImpactEventsMinimum Parameter = "Impact.MinimumImpactEvents"
+	ImpactEventsMaximum Parameter = "Impact.MaximumImpactEvents"
+	// ImpactEventsRange sets both the minimum and the maximum number of impact events to the same value.
+	ImpactEventsRange Parameter = "Impact.ImpactEventsRange"
+)
+
+// isImpactParameter reports whether the parameter belongs to an impact rather than to an event's probability.
+func isImpactParameter(parameter Parameter) bool {
+	switch parameter {
+	case ImpactUnitMinimum, ImpactUnitMaximum, ImpactUnitRange, ImpactEventsMinimum, ImpactEventsMaximum, ImpactEventsRange:
+		return true
+	}
+	return false
+}
+
+// Input identifies one parameter of the model.
+type Input struct {
+	EventID int `json:"EventID"`
+	// ImpactID selects the event's impact for impact parameters.
+	ImpactID  int       `json:"ImpactID,omitempty"`
+	Parameter Parameter `json:"Parameter"`
+}
+
+// withInputs returns a copy of events in which each input is set to its value.
+// Changed events, probabilities and impacts are copied, so the caller's events are left untouched.
+func withInputs(events []*risk.Event, values map[Input]float64) ([]*risk.Event, error) {
+	modified := make([]*risk.Event, len(events))
+	copy(modified, events)
+	index := make(map[int]int)
+	for i, event := range events {
+		if event != nil {
+			index[event.ID] = i
+		}
+	}
+
+	cloned := make(map[int]bool)
+	for input, value := range values {
+		i, ok := index[input.EventID]
+		if !ok {
+			return nil, fmt.Errorf("event %d not found", input.EventID)
+		}
+		if !cloned[input.EventID] {
+			clone := *modified[i]
+			if clone.Probability != nil {
+				probability := *clone.Probability
+				clone.Probability = &probability
+			}
+			if clone.Frequency != nil {
+				frequency := *clone.Frequency
+				clone.Frequency = &frequency
+			}
+			clone.Impact = make([]*risk.Impact, len(clone.Impact))
+			for j, impact := range events[i].Impact {
+				impactCopy := *impact
+				clone.Impact[j] = &impactCopy
+			}
+			modified[i] = &clone
+			cloned[input.EventID] = true
+		}
+		if err := setInput(modified[i], input, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return modified, nil
+}
+
+// setInput sets one input of an event that is already a private copy.
+func setInput(event *risk.Event, input Input, value float64) error {
+	switch input.Parameter {
+	case FrequencyMinimum, FrequencyMaximum, FrequencyRange:
+		if event.Frequency == nil {
+			return fmt.Errorf("event %d (%s) has no frequency", event.ID, event.Name)
+		}
+		if input.Parameter != FrequencyMaximum {
+			event.Frequency.Minimum = value
+		}
+		if input.Parameter != FrequencyMinimum {
+			event.Frequency.Maximum = value
+		}
+		return nil
+	}
+
+	if !isImpactParameter(input.Parameter) {
+		if event.Probability == nil {
+			return fmt.Errorf("event %d (%s) has no probability", event.ID, event.Name)
+		}
+		switch input.Parameter {
+		case ProbabilityMinimum:
+			event.Probability.Minimum = value
+		case ProbabilityMaximum:
+			event.Probability.Maximum = value
+		case ProbabilityRange:
+			event.Probability.Minimum = value
+			event.Probability.Maximum = value
+		default:
+			return fmt.Errorf("unknown parameter %q", input.Parameter)
+		}
+		return nil
+	}
+
+	for _, impact := range event.Impact {
+		if impact.ImpactID != input.ImpactID {
+			continue
+		}
+		switch input.Parameter {
+		case ImpactUnitMinimum:
+			impact.MinimumIndividualUnitImpact = value
+		case ImpactUnitMaximum:
+			impact.MaximumIndividualUnitImpact = value
+		case ImpactUnitRange:
+			impact.MinimumIndividualUnitImpact = value
+			impact.MaximumIndividualUnitImpact = value
+		case ImpactEventsMinimum:
+			impact.MinimumImpactEvents = value
+		case ImpactEventsMaximum:
+			impact.MaximumImpactEvents = value
+		case ImpactEventsRange:
+			impact.MinimumImpactEvents = value
+			impact.MaximumImpactEvents = value
+		}
+		return nil
+	}
+	return fmt.Errorf("impact %d not found on event %d (%s)", input.ImpactID, event.ID, event.Name)
+}
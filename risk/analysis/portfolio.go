@@ -0,0 +1,242 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/utils"
+	"golang.org/x/exp/rand"
+)
+
+// ExhaustiveSearchLimit is the largest number of candidate controls for which every portfolio is evaluated.
+// Larger sets are searched with simulated annealing.
+const ExhaustiveSearchLimit = 12
+
+// DefaultAnnealingSteps is the number of portfolios evaluated by simulated annealing when none is given.
+const DefaultAnnealingSteps = 500
+
+// CandidateControl is a control event in the model that may or may not be bought.
+// Candidates that are not selected in a portfolio are disabled during its simulation.
+type CandidateControl struct {
+	EventID int     `json:"EventID"`
+	Cost    float64 `json:"Cost"`
+}
+
+// LossMeasure selects the statistic of the yearly loss distribution of a unit to minimize.
+type LossMeasure struct {
+	Unit string `json:"Unit"`
+	// Percentile of the yearly loss distribution, e.g. 0.95. Zero uses the expected (mean) yearly loss.
+	Percentile float64 `json:"Percentile,omitempty"`
+}
+
+// PortfolioResult is a set of selected candidate controls with its total cost and modeled risk.
+type PortfolioResult struct {
+	Controls []string `json:"Controls"`
+	Cost     float64  `json:"Cost"`
+	Risk     float64  `json:"Risk"`
+}
+
+// Portfolio is the result of a control portfolio optimization.
+type Portfolio struct {
+	Iterations int                `json:"Iterations"`
+	Budget     float64            `json:"Budget"`
+	Measure    LossMeasure        `json:"Measure"`
+	Exhaustive bool               `json:"Exhaustive"`
+	Evaluated  int                `json:"Evaluated"`
+	Best       *PortfolioResult   `json:"Best"`
+	Frontier   []*PortfolioResult `json:"Frontier"`
+}
+
+// OptimizePortfolio searches for the subset of candidate controls whose total cost is within budget and that
+// minimizes the chosen loss measure. Every portfolio is simulated with common random numbers.
+// Up to ExhaustiveSearchLimit candidates every subset is evaluated, otherwise simulated annealing evaluates
+// annealingSteps portfolios, or DefaultAnnealingSteps when it is zero.
+// The Pareto frontier of cost versus risk over all evaluated portfolios is returned alongside the best one.
+func OptimizePortfolio(events []*risk.Event, candidates []*CandidateControl, budget float64, measure LossMeasure, annealingSteps, iterations int) (*Portfolio, error) {
+	return OptimizePortfolioSeeded(events, candidates, budget, measure, annealingSteps, iterations, time.Now().UnixNano())
+}
+
+// OptimizePortfolioSeeded is OptimizePortfolio with a fixed seed.
+func OptimizePortfolioSeeded(events []*risk.Event, candidates []*CandidateControl, budget float64, measure LossMeasure, annealingSteps, iterations int, seed int64) (*Portfolio, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	if annealingSteps < 0 {
+		return nil, fmt.Errorf("annealing steps cannot be negative, got %d", annealingSteps)
+	}
+	if annealingSteps == 0 {
+		annealingSteps = DefaultAnnealingSteps
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no candidate controls given")
+	}
+	if measure.Percentile < 0 || measure.Percentile >= 1 {
+		return nil, fmt.Errorf("percentile must be in [0, 1), got %f", measure.Percentile)
+	}
+	names := make([]string, len(candidates))
+	seen := make(map[int]bool, len(candidates))
+	for i, candidate := range candidates {
+		event := utils.FindEvent(candidate.EventID, events)
+		if event == nil {
+			return nil, fmt.Errorf("candidate control %d not found", candidate.EventID)
+		}
+		if !event.Control {
+			return nil, fmt.Errorf("candidate %d (%s) is not a control", event.ID, event.Name)
+		}
+		if seen[event.ID] {
+			return nil, fmt.Errorf("candidate control %d (%s) is listed more than once", event.ID, event.Name)
+		}
+		if candidate.Cost < 0 {
+			return nil, fmt.Errorf("candidate control %s has a negative cost", event.Name)
+		}
+		seen[event.ID] = true
+		names[i] = event.Name
+	}
+	if _, err := newSimulation(events, seed); err != nil {
+		return nil, err
+	}
+
+	evaluated := make(map[string]*PortfolioResult)
+	evaluate := func(selected []bool) *PortfolioResult {
+		key := selectionKey(selected)
+		if result, ok := evaluated[key]; ok {
+			return result
+		}
+
+		sim, _ := newSimulation(events, seed)
+		result := &PortfolioResult{Controls: []string{}}
+		for i, candidate := range candidates {
+			if selected[i] {
+				result.Controls = append(result.Controls, names[i])
+				result.Cost += candidate.Cost
+			} else {
+				sim.forced[candidate.EventID] = false
+			}
+		}
+		result.Risk = measureLoss(sim, iterations, measure)
+		evaluated[key] = result
+		return result
+	}
+
+	portfolio := &Portfolio{
+		Iterations: iterations,
+		Budget:     budget,
+		Measure:    measure,
+		Exhaustive: len(candidates) <= ExhaustiveSearchLimit,
+	}
+
+	if portfolio.Exhaustive {
+		for mask := 0; mask < 1<<len(candidates); mask++ {
+			selected := make([]bool, len(candidates))
+			for i := range selected {
+				selected[i] = mask&(1<<i) != 0
+			}
+			evaluate(selected)
+		}
+	} else {
+		annealPortfolio(candidates, budget, evaluate, annealingSteps, rand.New(rand.NewSource(uint64(seed))))
+	}
+
+	// Portfolios of equal cost and risk are ordered by their selection key, so Best and Frontier do not
+	// depend on map iteration order.
+	keys := make([]string, 0, len(evaluated))
+	for key := range evaluated {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	results := make([]*PortfolioResult, len(keys))
+	for i, key := range keys {
+		results[i] = evaluated[key]
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Cost != results[j].Cost {
+			return results[i].Cost < results[j].Cost
+		}
+		return results[i].Risk < results[j].Risk
+	})
+
+	for _, result := range results {
+		if result.Cost <= budget && (portfolio.Best == nil || result.Risk < portfolio.Best.Risk) {
+			portfolio.Best = result
+		}
+		// Sorted by cost, a portfolio is on the frontier if it has less risk than every cheaper one.
+		if len(portfolio.Frontier) == 0 || result.Risk < portfolio.Frontier[len(portfolio.Frontier)-1].Risk {
+			portfolio.Frontier = append(portfolio.Frontier, result)
+		}
+	}
+	portfolio.Evaluated = len(results)
+
+	if portfolio.Best == nil {
+		return nil, fmt.Errorf("no portfolio fits within the budget of %f", budget)
+	}
+
+	return portfolio, nil
+}
+
+// annealPortfolio explores portfolios within budget by flipping one candidate at a time,
+// accepting worse portfolios with a probability that falls as the temperature cools over the given steps.
+func annealPortfolio(candidates []*CandidateControl, budget float64, evaluate func([]bool) *PortfolioResult, steps int, rng *rand.Rand) {
+	current := make([]bool, len(candidates))
+	currentResult := evaluate(current)
+
+	temperature := math.Max(math.Abs(currentResult.Risk)*0.1, 1e-9)
+	cooling := math.Pow(1e-3, 1/float64(steps))
+
+	for step := 0; step < steps; step++ {
+		next := append([]bool(nil), current...)
+		flip := rng.Intn(len(candidates))
+		next[flip] = !next[flip]
+
+		// Drop random selected controls until the portfolio is affordable again.
+		for cost := selectionCost(candidates, next); cost > budget; cost = selectionCost(candidates, next) {
+			var selected []int
+			for i, s := range next {
+				if s && i != flip {
+					selected = append(selected, i)
+				}
+			}
+			if len(selected) == 0 {
+				next[flip] = false
+				break
+			}
+			next[selected[rng.Intn(len(selected))]] = false
+		}
+
+		nextResult := evaluate(next)
+		delta := nextResult.Risk - currentResult.Risk
+		if delta <= 0 || rng.Float64() < math.Exp(-delta/temperature) {
+			current, currentResult = next, nextResult
+		}
+		temperature *= cooling
+	}
+}
+
+// measureLoss simulates the model and returns the chosen statistic of the unit's yearly loss.
+func measureLoss(sim *simulation, iterations int, measure LossMeasure) float64 {
+	loss, _ := measureOutput(sim, iterations, Output{Loss: &measure})
+	return loss
+}
+
+func selectionCost(candidates []*CandidateControl, selected []bool) float64 {
+	var cost float64
+	for i, s := range selected {
+		if s {
+			cost += candidates[i].Cost
+		}
+	}
+	return cost
+}
+
+func selectionKey(selected []bool) string {
+	key := make([]byte, len(selected))
+	for i, s := range selected {
+		key[i] = '0'
+		if s {
+			key[i] = '1'
+		}
+	}
+	return string(key)
+}
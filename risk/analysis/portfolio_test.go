@@ -0,0 +1,51 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPortfolioTiesAreDeterministic(t *testing.T) {
+	// The second control protects nothing, so every portfolio has a twin of equal cost and risk.
+	idle := testEvent(4, "Idle Control", 0.5)
+	idle.Control = true
+	idle.Impact = nil
+	events := append(testModel(), idle)
+	candidates := []*CandidateControl{{EventID: 2, Cost: 10}, {EventID: 4, Cost: 0}}
+
+	// Within a budget of 0, buying the idle control or nothing ties; the portfolio without it comes first.
+	free, err := OptimizePortfolioSeeded(events, candidates, 0, LossMeasure{Unit: "USD"}, 0, 500, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(free.Best.Controls) != 0 {
+		t.Errorf("best free portfolio %v, want none of the controls", free.Best.Controls)
+	}
+
+	first, err := OptimizePortfolioSeeded(events, candidates, 10, LossMeasure{Unit: "USD"}, 0, 500, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for run := 0; run < 20; run++ {
+		again, err := OptimizePortfolioSeeded(events, candidates, 10, LossMeasure{Unit: "USD"}, 0, 500, testSeed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(first, again) {
+			t.Fatalf("run %d gave best %v and frontier %v, first run %v and %v", run, again.Best, again.Frontier, first.Best, first.Frontier)
+		}
+	}
+}
+
+func TestPortfolioRejectsInvalidCandidates(t *testing.T) {
+	for name, candidates := range map[string][]*CandidateControl{
+		"not a control": {{EventID: 1, Cost: 10}},
+		"duplicate":     {{EventID: 2, Cost: 10}, {EventID: 2, Cost: 5}},
+		"negative cost": {{EventID: 2, Cost: -10}},
+		"missing":       {{EventID: 9, Cost: 10}},
+	} {
+		if _, err := OptimizePortfolioSeeded(testModel(), candidates, 100, LossMeasure{Unit: "USD"}, 0, 10, testSeed); err == nil {
+			t.Errorf("%s: candidates were accepted", name)
+		}
+	}
+}
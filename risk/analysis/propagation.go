@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/utils"
+)
+
+// Propagation is how the occurrences of frequency events flow to the events that depend on them.
+type Propagation string
+
+const (
+	// PropagateEpisodes simulates the events that depend on a frequency event in a separate episode for each
+	// of its occurrences, so every occurrence can trigger its own chain of events.
+	PropagateEpisodes Propagation = "episodes"
+	// PropagateThinning simulates every event once per year and draws its count as a binomial thinning of the
+	// counts of the events it requires: N phishing emails, thinned by the filter and then by the employee's
+	// click probability, and so on down the tree.
+	PropagateThinning Propagation = "thinning"
+)
+
+// setPropagation sets how the simulation propagates counts; an empty propagation uses PropagateEpisodes.
+func (s *simulation) setPropagation(propagation Propagation) error {
+	switch propagation {
+	case "":
+		s.propagation = PropagateEpisodes
+	case PropagateEpisodes, PropagateThinning:
+		s.propagation = propagation
+	default:
+		return fmt.Errorf("unknown count propagation %q", propagation)
+	}
+	return nil
+}
+
+// thin simulates every event once, in order, drawing each event's count as binomial thinning of the
+// occurrences of the events it requires.
+func (s *simulation) thin(iteration int, o *outcome) {
+	eventsOccurred := make(map[int]bool, len(s.events))
+	for _, event := range s.events {
+		trials, siblings := s.funnel(event, o.counts)
+		// Siblings already took their share of the trials, so they do not lower the probability as well.
+		occurred := eventsOccurred
+		if len(siblings) > 0 {
+			occurred = make(map[int]bool, len(eventsOccurred))
+			for eventID, happened := range eventsOccurred {
+				occurred[eventID] = happened && !siblings[eventID]
+			}
+		}
+		p := UpdateEventProbabilityWithDependency(event, occurred, o.probabilities)
+		s.decide(iteration, nil, event, p, eventsOccurred, trials, o)
+	}
+}
+
+// funnel returns the number of chances an event has to occur: the fewest occurrences among the events it
+// requires to have happened, less the occurrences of its siblings, or one if it requires none. Siblings are
+// the events it requires not to have happened that are drawn from the same occurrences, such as a mail filter
+// that catches some of the phishing emails an employee could otherwise click; they are returned too. Other
+// events it requires not to have happened only lower its probability.
+func (s *simulation) funnel(event *risk.Event, counts map[int]int) (int, map[int]bool) {
+	n := -1
+	required := make(map[int]bool)
+	for _, dependency := range event.Dependencies {
+		if dependency.Happens {
+			required[dependency.DependsOnEventID] = true
+			if n < 0 || counts[dependency.DependsOnEventID] < n {
+				n = counts[dependency.DependsOnEventID]
+			}
+		}
+	}
+	if n < 0 {
+		return 1, nil
+	}
+
+	var siblings map[int]bool
+	for _, dependency := range event.Dependencies {
+		if dependency.Happens {
+			continue
+		}
+		sibling := utils.FindEvent(dependency.DependsOnEventID, s.events)
+		if sibling == nil {
+			continue
+		}
+		for _, parent := range sibling.Dependencies {
+			if parent.Happens && required[parent.DependsOnEventID] {
+				if siblings == nil {
+					siblings = make(map[int]bool)
+				}
+				siblings[sibling.ID] = true
+				n -= counts[sibling.ID]
+				break
+			}
+		}
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n, siblings
+}
@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"gonum.org/v1/gonum/stat"
+)
+
+// funnelModel is phishing emails (1), some caught by a filter (2), the rest clicked by an employee (3).
+func funnelModel() []*risk.Event {
+	phishing := testEvent(1, "Phishing", 0)
+	phishing.Probability = nil
+	phishing.Frequency = &risk.Frequency{ExpectedFrequency: "yearly", Minimum: 3, MinimumConfidence: 0.9, Maximum: 3, MaximumConfidence: 0.9}
+	filter := testEvent(2, "Filter", 0.5, &risk.Dependency{DependsOnEventID: 1, Happens: true})
+	filter.Control = true
+	filter.Impact = nil
+	click := testEvent(3, "Click", 0.6,
+		&risk.Dependency{DependsOnEventID: 1, Happens: true},
+		&risk.Dependency{DependsOnEventID: 2, Happens: false})
+	return []*risk.Event{phishing, filter, click}
+}
+
+func TestThinningFunnel(t *testing.T) {
+	const iterations = 20000
+	sim, err := newSimulation(funnelModel(), testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.setPropagation(PropagateThinning); err != nil {
+		t.Fatal(err)
+	}
+
+	clicks := make([]float64, iterations)
+	for i := range clicks {
+		counts := sim.iterateOutcome(i).counts
+		if counts[2]+counts[3] > counts[1] {
+			t.Fatalf("iteration %d: %d caught and %d clicked out of %d emails", i, counts[2], counts[3], counts[1])
+		}
+		clicks[i] = float64(counts[3])
+	}
+
+	// Every email is caught or not, and every email that gets through is clicked or not.
+	expected := sim.rates[1] * (1 - sim.probabilities[2]) * sim.probabilities[3]
+	mean, std := stat.MeanStdDev(clicks, nil)
+	if tolerance := 4 * std / math.Sqrt(iterations); math.Abs(mean-expected) > tolerance {
+		t.Errorf("mean clicks %f, expected %f (tolerance %f)", mean, expected, tolerance)
+	}
+}
+
+func TestUnknownPropagationIsRejected(t *testing.T) {
+	if _, err := YearlyImpactsSeeded(testModel(), "bogus", 10, testSeed); err == nil {
+		t.Error("unknown propagation was accepted")
+	}
+}
@@ -0,0 +1,126 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// ScenarioPath is a distinct set of events that occurred together in an iteration, listed in model order.
+type ScenarioPath struct {
+	EventIDs    []int    `json:"EventIDs"`
+	Events      []string `json:"Events"`
+	Occurrences int      `json:"Occurrences"`
+	// Frequency is the share of iterations in which exactly these events occurred.
+	Frequency float64 `json:"Frequency"`
+	// MeanLoss is the average loss of an iteration following this path.
+	MeanLoss float64 `json:"MeanLoss"`
+	// ContributedLoss is this path's contribution to the expected yearly loss.
+	ContributedLoss float64 `json:"ContributedLoss"`
+	// LossShare is ContributedLoss as a share of the expected yearly loss.
+	LossShare float64 `json:"LossShare"`
+}
+
+// Scenarios holds the most common and the most costly ways the modeled risks unfold.
+type Scenarios struct {
+	Iterations   int             `json:"Iterations"`
+	LossUnit     string          `json:"LossUnit"`
+	ExpectedLoss float64         `json:"ExpectedLoss"`
+	Distinct     int             `json:"Distinct"`
+	ByFrequency  []*ScenarioPath `json:"ByFrequency"`
+	ByLoss       []*ScenarioPath `json:"ByLoss"`
+}
+
+// ScenarioPaths records the set of events that occurred in each iteration, aggregates identical sets, and
+// reports the top k paths by frequency and by their contribution to the expected yearly loss in lossUnit.
+func ScenarioPaths(events []*risk.Event, lossUnit string, k int, iterations int) (*Scenarios, error) {
+	return ScenarioPathsSeeded(events, lossUnit, k, iterations, time.Now().UnixNano())
+}
+
+// ScenarioPathsSeeded is ScenarioPaths with a fixed seed.
+func ScenarioPathsSeeded(events []*risk.Event, lossUnit string, k int, iterations int, seed int64) (*Scenarios, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	if k < 1 {
+		return nil, fmt.Errorf("k must be at least 1, got %d", k)
+	}
+	sim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]*ScenarioPath)
+	var totalLoss float64
+	sim.run(iterations, func(_ int, eventsOccurred map[int]bool, eventImpacts map[int]map[string]float64) {
+		ids := []int{}
+		var key strings.Builder
+		for _, event := range events {
+			if eventsOccurred[event.ID] {
+				ids = append(ids, event.ID)
+				key.WriteString(strconv.Itoa(event.ID))
+				key.WriteByte(',')
+			}
+		}
+
+		path, ok := paths[key.String()]
+		if !ok {
+			path = &ScenarioPath{EventIDs: ids, Events: []string{}}
+			for _, event := range events {
+				if eventsOccurred[event.ID] {
+					path.Events = append(path.Events, event.Name)
+				}
+			}
+			paths[key.String()] = path
+		}
+
+		loss := totalImpacts(eventImpacts)[lossUnit]
+		path.Occurrences++
+		path.ContributedLoss += loss
+		totalLoss += loss
+	})
+
+	scenarios := &Scenarios{
+		Iterations:   iterations,
+		LossUnit:     lossUnit,
+		ExpectedLoss: totalLoss / float64(iterations),
+		Distinct:     len(paths),
+	}
+
+	keys := make([]string, 0, len(paths))
+	for key := range paths {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	all := make([]*ScenarioPath, 0, len(paths))
+	for _, key := range keys {
+		path := paths[key]
+		path.Frequency = float64(path.Occurrences) / float64(iterations)
+		path.MeanLoss = path.ContributedLoss / float64(path.Occurrences)
+		if totalLoss != 0 {
+			path.LossShare = path.ContributedLoss / totalLoss
+		}
+		path.ContributedLoss /= float64(iterations)
+		all = append(all, path)
+	}
+
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Occurrences > all[j].Occurrences })
+	scenarios.ByFrequency = append([]*ScenarioPath(nil), all[:minInt(k, len(all))]...)
+
+	sort.SliceStable(all, func(i, j int) bool { return all[i].ContributedLoss > all[j].ContributedLoss })
+	scenarios.ByLoss = append([]*ScenarioPath(nil), all[:minInt(k, len(all))]...)
+
+	return scenarios, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
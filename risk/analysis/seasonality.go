@@ -0,0 +1,142 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// period is the span of time simulated by one iteration of the engine: all or part of a year of a horizon.
+type period struct {
+	// year counts from 0 for the first year of a horizon.
+	year int
+	// start and length are fractions of a year; a period does not extend past the end of its year.
+	start, length float64
+}
+
+// wholeYear is the period of a yearly simulation.
+var wholeYear = period{length: 1}
+
+// profile returns the seasonality and trend of an event's Probability or Frequency.
+func profile(event *risk.Event) (*risk.Seasonality, *risk.Trend) {
+	if event.Frequency != nil {
+		return event.Frequency.Seasonality, event.Frequency.Trend
+	}
+	return event.Probability.Seasonality, event.Probability.Trend
+}
+
+// validateProfile checks an event's seasonality and trend.
+func validateProfile(event *risk.Event) error {
+	seasonality, trend := profile(event)
+	if seasonality != nil {
+		if len(seasonality.Monthly) != 12 {
+			return fmt.Errorf("event %d (%s) seasonality needs 12 monthly multipliers, got %d", event.ID, event.Name, len(seasonality.Monthly))
+		}
+		for _, multiplier := range seasonality.Monthly {
+			if multiplier < 0 {
+				return fmt.Errorf("event %d (%s) seasonality multipliers cannot be negative", event.ID, event.Name)
+			}
+		}
+	}
+	if trend != nil {
+		if trend.Growth <= -1 {
+			return fmt.Errorf("event %d (%s) trend growth must be greater than -1", event.ID, event.Name)
+		}
+		for _, multiplier := range trend.Yearly {
+			if multiplier < 0 {
+				return fmt.Errorf("event %d (%s) trend multipliers cannot be negative", event.ID, event.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// factor is how much an event's yearly rate is scaled over the period: the share of the year it covers,
+// times the mean seasonal multiplier of its months, times the trend of its year.
+func (at period) factor(event *risk.Event) float64 {
+	seasonality, trend := profile(event)
+	return at.length * seasonalFactor(seasonality, at.start, at.length) * trendFactor(trend, at.year)
+}
+
+// seasonalFactor is the mean monthly multiplier over the part of the year from start to start+length.
+// Every month is taken to be a twelfth of the year.
+func seasonalFactor(seasonality *risk.Seasonality, start, length float64) float64 {
+	if seasonality == nil || length <= 0 {
+		return 1
+	}
+	var sum float64
+	for month, multiplier := range seasonality.Monthly {
+		from := math.Max(start, float64(month)/12)
+		to := math.Min(start+length, float64(month+1)/12)
+		if to > from {
+			sum += multiplier * (to - from)
+		}
+	}
+	return sum / length
+}
+
+// seasonalTime maps a uniform draw u to a time between from and the end of the year, as a fraction of the year,
+// with a density proportional to the monthly multipliers of the seasonality; without one the time is uniform.
+func seasonalTime(seasonality *risk.Seasonality, from, u float64) float64 {
+	if seasonality != nil {
+		masses := make([]float64, len(seasonality.Monthly))
+		var total float64
+		for month, multiplier := range seasonality.Monthly {
+			if to := float64(month+1) / 12; to > from {
+				masses[month] = multiplier * (to - math.Max(from, float64(month)/12))
+				total += masses[month]
+			}
+		}
+		target := u * total
+		for month, mass := range masses {
+			if mass > 0 && target < mass {
+				start := math.Max(from, float64(month)/12)
+				return start + target/seasonality.Monthly[month]
+			}
+			target -= mass
+		}
+	}
+	return from + u*(1-from)
+}
+
+// trendFactor is the trend multiplier of a year of a horizon, counted from 0.
+func trendFactor(trend *risk.Trend, year int) float64 {
+	if trend == nil {
+		return 1
+	}
+	if n := len(trend.Yearly); n > 0 {
+		if year < n {
+			return trend.Yearly[year]
+		}
+		return trend.Yearly[n-1]
+	}
+	return math.Pow(1+trend.Growth, float64(year))
+}
+
+// estimates returns each event's probability of occurring in the period and the expected number of
+// occurrences of each frequency event. A probability is scaled as the hazard of a constant rate, so a yearly
+// probability p becomes 1-(1-p)^f over a period scaled by f.
+func (s *simulation) estimates(at period) (map[int]float64, map[int]float64) {
+	if at == wholeYear && !s.profiled {
+		return s.probabilities, s.rates
+	}
+	probabilities := make(map[int]float64, len(s.probabilities))
+	rates := make(map[int]float64, len(s.rates))
+	for _, event := range s.events {
+		f := at.factor(event)
+		if event.Frequency != nil {
+			rates[event.ID] = s.rates[event.ID] * f
+			probabilities[event.ID] = atLeastOnce(event.Frequency, rates[event.ID])
+			continue
+		}
+		p := s.probabilities[event.ID]
+		if p < 1 {
+			p = 1 - math.Pow(1-p, f)
+		} else if f == 0 {
+			p = 0
+		}
+		probabilities[event.ID] = p
+	}
+	return probabilities, rates
+}
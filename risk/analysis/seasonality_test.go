@@ -0,0 +1,98 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// holidays is a seasonality with triple the usual rate in December.
+func holidays() *risk.Seasonality {
+	monthly := make([]float64, 12)
+	for month := range monthly {
+		monthly[month] = 1
+	}
+	monthly[11] = 3
+	return &risk.Seasonality{Monthly: monthly}
+}
+
+func TestSeasonalFactor(t *testing.T) {
+	seasonality := holidays()
+	tests := []struct {
+		start, length float64
+		want          float64
+	}{
+		{0, 1, 14.0 / 12},
+		{11.0 / 12, 1.0 / 12, 3},
+		{0, 1.0 / 12, 1},
+		{10.0 / 12, 2.0 / 12, 2},
+	}
+	for _, test := range tests {
+		if got := seasonalFactor(seasonality, test.start, test.length); math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("from %f for %f: got %f, want %f", test.start, test.length, got, test.want)
+		}
+	}
+	if got := seasonalFactor(nil, 0.5, 0.25); got != 1 {
+		t.Errorf("without a seasonality got %f, want 1", got)
+	}
+
+	// Times drawn from the seasonality fall in December with its share of the year's mass.
+	var december int
+	const draws = 12000
+	for i := 0; i < draws; i++ {
+		time := seasonalTime(seasonality, 0, (float64(i)+0.5)/draws)
+		if time < 0 || time >= 1 {
+			t.Fatalf("time %f outside the year", time)
+		}
+		if time >= 11.0/12 {
+			december++
+		}
+	}
+	if share := float64(december) / draws; math.Abs(share-3.0/14) > 1e-3 {
+		t.Errorf("%f of the times fell in December, want %f", share, 3.0/14)
+	}
+	if time := seasonalTime(seasonality, 0.5, 0); time < 0.5 {
+		t.Errorf("time %f is before the start 0.5", time)
+	}
+}
+
+func TestTrendFactor(t *testing.T) {
+	growth := &risk.Trend{Growth: 0.1}
+	for year, want := range []float64{1, 1.1, 1.21} {
+		if got := trendFactor(growth, year); math.Abs(got-want) > 1e-12 {
+			t.Errorf("growth in year %d: got %f, want %f", year, got, want)
+		}
+	}
+	explicit := &risk.Trend{Growth: 0.5, Yearly: []float64{1, 2}}
+	for year, want := range []float64{1, 2, 2} {
+		if got := trendFactor(explicit, year); got != want {
+			t.Errorf("explicit multipliers in year %d: got %f, want %f", year, got, want)
+		}
+	}
+	if got := trendFactor(nil, 3); got != 1 {
+		t.Errorf("without a trend got %f, want 1", got)
+	}
+}
+
+func TestProbabilitiesScaleAsHazards(t *testing.T) {
+	events := testModel()
+	events[0].Probability.Trend = &risk.Trend{Yearly: []float64{1, 2}}
+	sim, err := newSimulation(events, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := sim.probabilities[1]
+	probabilities, _ := sim.estimates(period{year: 1, length: 1})
+	if want := 1 - math.Pow(1-p, 2); math.Abs(probabilities[1]-want) > 1e-12 {
+		t.Errorf("doubled yearly probability %f became %f, want %f", p, probabilities[1], want)
+	}
+	if probabilities[3] != sim.probabilities[3] {
+		t.Errorf("an event without a trend changed from %f to %f", sim.probabilities[3], probabilities[3])
+	}
+
+	events[0].Probability.Seasonality = &risk.Seasonality{Monthly: []float64{1, 2}}
+	if _, err := newSimulation(events, testSeed); err == nil {
+		t.Error("a seasonality without 12 months was accepted")
+	}
+}
@@ -0,0 +1,191 @@
+package analysis
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// TornadoBar is the change in the output when one input is swung from its low to its high value.
+type TornadoBar struct {
+	Input      Input   `json:"Input"`
+	Label      string  `json:"Label"`
+	LowValue   float64 `json:"LowValue"`
+	HighValue  float64 `json:"HighValue"`
+	LowOutput  float64 `json:"LowOutput"`
+	HighOutput float64 `json:"HighOutput"`
+	// Swing is the absolute difference between HighOutput and LowOutput, used to rank the bars.
+	Swing float64 `json:"Swing"`
+}
+
+// Sensitivity is the result of a one-at-a-time sensitivity analysis, ranked by swing.
+type Sensitivity struct {
+	Iterations int           `json:"Iterations"`
+	Output     Output        `json:"Output"`
+	BaseOutput float64       `json:"BaseOutput"`
+	Bars       []*TornadoBar `json:"Bars"`
+}
+
+// OneAtATime swings each event's probability or frequency bounds, and each impact's unit impact and impact
+// event bounds, between their low and high values while holding every other input at its modeled bounds.
+// The low value sets both bounds to the minimum and the high value sets both bounds to the maximum;
+// inputs whose bounds are equal are swung by the relative swing instead, e.g. 0.1 swings a value of 10
+// between 9 and 11.
+// Each swing is simulated with common random numbers and the bars are ranked by the change in the output.
+func OneAtATime(events []*risk.Event, output Output, swing float64, iterations int) (*Sensitivity, error) {
+	return OneAtATimeSeeded(events, output, swing, iterations, time.Now().UnixNano())
+}
+
+// OneAtATimeSeeded is OneAtATime with a fixed seed.
+func OneAtATimeSeeded(events []*risk.Event, output Output, swing float64, iterations int, seed int64) (*Sensitivity, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	if err := validateSwing(swing); err != nil {
+		return nil, err
+	}
+	if err := output.validate(events); err != nil {
+		return nil, err
+	}
+
+	evaluate := func(values map[Input]float64) (float64, error) {
+		modified, err := withInputs(events, values)
+		if err != nil {
+			return 0, err
+		}
+		sim, err := newSimulation(modified, seed)
+		if err != nil {
+			return 0, err
+		}
+		value, _ := measureOutput(sim, iterations, output)
+		return value, nil
+	}
+
+	base, err := evaluate(nil)
+	if err != nil {
+		return nil, err
+	}
+	sensitivity := &Sensitivity{Iterations: iterations, Output: output, BaseOutput: base}
+
+	for _, bounds := range uncertainInputs(events) {
+		low, high := bounds.swung(swing)
+
+		lowOutput, err := evaluate(map[Input]float64{bounds.input: low})
+		if err != nil {
+			return nil, err
+		}
+		highOutput, err := evaluate(map[Input]float64{bounds.input: high})
+		if err != nil {
+			return nil, err
+		}
+
+		sensitivity.Bars = append(sensitivity.Bars, &TornadoBar{
+			Input:      bounds.input,
+			Label:      bounds.label,
+			LowValue:   low,
+			HighValue:  high,
+			LowOutput:  lowOutput,
+			HighOutput: highOutput,
+			Swing:      math.Abs(highOutput - lowOutput),
+		})
+	}
+
+	sort.SliceStable(sensitivity.Bars, func(i, j int) bool {
+		return sensitivity.Bars[i].Swing > sensitivity.Bars[j].Swing
+	})
+
+	return sensitivity, nil
+}
+
+// WriteCSV writes the tornado table as CSV, one ranked bar per row, for charting.
+func (s *Sensitivity) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Label", "LowValue", "HighValue", "LowOutput", "HighOutput", "BaseOutput", "Swing"}); err != nil {
+		return err
+	}
+	for _, bar := range s.Bars {
+		record := []string{bar.Label}
+		for _, v := range []float64{bar.LowValue, bar.HighValue, bar.LowOutput, bar.HighOutput, s.BaseOutput, bar.Swing} {
+			record = append(record, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// inputBounds is an uncertain input of the model with its modeled minimum and maximum.
+type inputBounds struct {
+	input    Input
+	label    string
+	min, max float64
+	// probability bounds cannot be swung above 1.
+	probability bool
+}
+
+// swung returns the low and high values of the input, applying the relative swing when its bounds are equal.
+func (b inputBounds) swung(swing float64) (float64, float64) {
+	low, high := b.min, b.max
+	if low == high {
+		low, high = low*(1-swing), high*(1+swing)
+	}
+	if b.probability {
+		high = math.Min(high, 1)
+	}
+	return low, high
+}
+
+// validateSwing checks that a relative swing keeps non-negative inputs non-negative.
+func validateSwing(swing float64) error {
+	if swing < 0 || swing > 1 {
+		return fmt.Errorf("swing must be in [0, 1], got %f", swing)
+	}
+	return nil
+}
+
+// uncertainInputs lists every event's probability or frequency bounds and every impact's unit impact and
+// impact event bounds.
+func uncertainInputs(events []*risk.Event) []inputBounds {
+	var inputs []inputBounds
+	for _, event := range events {
+		if event.Probability != nil {
+			inputs = append(inputs, inputBounds{
+				input:       Input{EventID: event.ID, Parameter: ProbabilityRange},
+				label:       event.Name + " / Probability",
+				min:         event.Probability.Minimum,
+				max:         event.Probability.Maximum,
+				probability: event.Probability.Kind != risk.KindRate,
+			})
+		}
+		if event.Frequency != nil {
+			inputs = append(inputs, inputBounds{
+				input: Input{EventID: event.ID, Parameter: FrequencyRange},
+				label: event.Name + " / Frequency",
+				min:   event.Frequency.Minimum,
+				max:   event.Frequency.Maximum,
+			})
+		}
+		for _, impact := range event.Impact {
+			inputs = append(inputs, inputBounds{
+				input: Input{EventID: event.ID, ImpactID: impact.ImpactID, Parameter: ImpactUnitRange},
+				label: event.Name + " / " + impact.Name + " / Individual Unit Impact",
+				min:   impact.MinimumIndividualUnitImpact,
+				max:   impact.MaximumIndividualUnitImpact,
+			}, inputBounds{
+				input: Input{EventID: event.ID, ImpactID: impact.ImpactID, Parameter: ImpactEventsRange},
+				label: event.Name + " / " + impact.Name + " / Impact Events",
+				min:   impact.MinimumImpactEvents,
+				max:   impact.MaximumImpactEvents,
+			})
+		}
+	}
+	return inputs
+}
@@ -0,0 +1,38 @@
+package analysis
+
+import (
+	"testing"
+)
+
+func TestFrequencyInputs(t *testing.T) {
+	events := funnelModel()
+	frequency := Input{EventID: 1, Parameter: FrequencyRange}
+
+	sensitivity, err := OneAtATimeSeeded(events, Output{EventID: 3}, 0.5, 2000, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, bar := range sensitivity.Bars {
+		if bar.Input == frequency {
+			found = true
+			if bar.LowValue != 1.5 || bar.HighValue != 4.5 || bar.HighOutput <= bar.LowOutput {
+				t.Errorf("frequency bar %+v does not raise the click probability", bar)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("no tornado bar for the phishing frequency")
+	}
+
+	result, err := GoalSeekSeeded(events, frequency, 0.1, 10, Goal{Output: Output{EventID: 3}, Value: 0.5}, 0.01, 2000, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Value <= 0.1 || result.Value >= 10 {
+		t.Errorf("goal seek on the frequency ended at the bound %f", result.Value)
+	}
+	if events[0].Frequency.Minimum != 3 || events[0].Frequency.Maximum != 3 {
+		t.Errorf("the caller's frequency was changed to %+v", events[0].Frequency)
+	}
+}
@@ -1,13 +1,14 @@
 package analysis
 
 import (
+	"fmt"
 	"math"
-	"math/rand"
 	"time"
 
 	"github.com/bcdannyboy/dgws/risk"
 	"github.com/bcdannyboy/dgws/risk/statistics"
 	"github.com/bcdannyboy/dgws/risk/utils"
+	"golang.org/x/exp/rand"
 )
 
 // UpdateEventProbabilityWithDependency updates the event probability based on the outcome of its dependencies using Bayesian principles.
@@ -34,14 +35,35 @@ func UpdateEventProbabilityWithDependency(event *risk.Event, eventsOccurred map[
 }
 
 // SimulateEvent checks if an event happens based on its probability and dependencies.
-func SimulateEvent(event *risk.Event, eventsOccurred map[int]bool, eventProbabilities map[int]float64) (bool, map[string]float64) {
-	rand.Seed(time.Now().UnixNano())
+// When it happens, the stakeholders of each secondary loss react with the weighted average of its
+// reaction probability, and the impacts include the sampled magnitude of every reaction.
+// An impact with an unknown ExpectedFrequency is an error.
+func SimulateEvent(event *risk.Event, eventsOccurred map[int]bool, eventProbabilities map[int]float64) (bool, map[string]float64, error) {
+	for _, impact := range event.Impact {
+		if _, err := utils.PeriodsPerYear(impact.ExpectedFrequency); err != nil {
+			return false, nil, fmt.Errorf("event %d (%s) impact %s: %w", event.ID, event.Name, impact.Name, err)
+		}
+	}
+	happened, impacts := simulateEvent(event, eventsOccurred, eventProbabilities, rand.New(rand.NewSource(uint64(time.Now().UnixNano()))))
+	return happened, impacts, nil
+}
+
+// simulateEvent checks if an event happens and which secondary losses it triggers, drawing from rng.
+func simulateEvent(event *risk.Event, eventsOccurred map[int]bool, eventProbabilities map[int]float64, rng *rand.Rand) (bool, map[string]float64) {
 	adjustedProbability := UpdateEventProbabilityWithDependency(event, eventsOccurred, eventProbabilities)
 
-	if rand.Float64() <= adjustedProbability {
+	if rng.Float64() < adjustedProbability {
 		eventsOccurred[event.ID] = true
 		// Adjust impacts based on the event's role in the simulation, such as filtering phishing emails.
 		impacts := calculateImpacts(event, eventProbabilities)
+		for _, impact := range event.Impact {
+			if reaction := impact.Secondary; reaction != nil {
+				p := weightedAverageWithConfidence(reaction.Minimum, reaction.Maximum, reaction.MinimumConfidence, reaction.MaximumConfidence)
+				if rng.Float64() < p {
+					impacts[impact.Unit] += sampleImpact(rng, impact, eventProbabilities[event.ID])
+				}
+			}
+		}
 		return true, impacts
 	}
 	eventsOccurred[event.ID] = false
@@ -49,31 +71,55 @@ func SimulateEvent(event *risk.Event, eventsOccurred map[int]bool, eventProbabil
 }
 
 // calculateImpacts has been updated to include confidence levels in its calculations.
+// Secondary losses are left out; they depend on stakeholder reactions drawn by the simulation.
 func calculateImpacts(event *risk.Event, eventProbabilities map[int]float64) map[string]float64 {
 	impacts := make(map[string]float64)
 	for _, impact := range event.Impact {
-		// Adjust the impact calculation to factor in confidence levels for both unit impacts and event numbers.
-		actualUnitImpact, actualEvents := adjustImpactBasedOnEventProbability(impact, eventProbabilities[event.ID])
-
-		totalImpact := actualUnitImpact * actualEvents
-		if impact.PositiveImpact {
-			totalImpact = -totalImpact // Adjust for positive impacts if necessary.
+		if impact.Secondary != nil {
+			continue
 		}
-
-		impacts[impact.Unit] += totalImpact
+		impacts[impact.Unit] += impactValue(impact, eventProbabilities[event.ID])
 	}
 
 	return impacts
 }
 
+// impactValue is the total of one impact when its event occurs once.
+func impactValue(impact *risk.Impact, eventProbability float64) float64 {
+	// Adjust the impact calculation to factor in confidence levels for both unit impacts and event numbers.
+	actualUnitImpact, actualEvents := adjustImpactBasedOnEventProbability(impact, eventProbability)
+
+	totalImpact := actualUnitImpact * actualEvents
+	if impact.PositiveImpact {
+		totalImpact = -totalImpact // Adjust for positive impacts if necessary.
+	}
+	return totalImpact
+}
+
+// sampleImpact draws the total of one impact for a single occurrence, or a single reaction to one, from
+// PERT distributions over its unit impact and number of impact events, between their bounds and most
+// likely at the values impactValue uses.
+func sampleImpact(rng *rand.Rand, impact *risk.Impact, eventProbability float64) float64 {
+	avgUnitImpact, avgEvents := adjustImpactBasedOnEventProbability(impact, eventProbability)
+	unitImpact := statistics.GeneratePERTSampleFrom(rng, impact.MinimumIndividualUnitImpact, avgUnitImpact, impact.MaximumIndividualUnitImpact)
+	events := math.Round(statistics.GeneratePERTSampleFrom(rng, impact.MinimumImpactEvents, avgEvents, impact.MaximumImpactEvents))
+
+	totalImpact := unitImpact * events
+	if impact.PositiveImpact {
+		totalImpact = -totalImpact
+	}
+	return totalImpact
+}
+
 // adjustImpactBasedOnEventProbability is refined to consider confidence levels.
+// The impact's timeframe must be known, as newSimulation and SimulateEvent check.
 func adjustImpactBasedOnEventProbability(impact *risk.Impact, eventProbability float64) (float64, float64) {
 	// Calculate the average impact and events considering the confidence intervals.
 
-	scaledMinIndividualUnitImpact := utils.AdjustForTime(impact.MinimumIndividualUnitImpact, impact.ExpectedFrequency)
-	scaledMaxIndividualUnitImpact := utils.AdjustForTime(impact.MaximumIndividualUnitImpact, impact.ExpectedFrequency)
-	scaledMinImpactEvents := utils.AdjustForTime(impact.MinimumImpactEvents, impact.ExpectedFrequency)
-	scaledMaxImpactEvents := utils.AdjustForTime(impact.MaximumImpactEvents, impact.ExpectedFrequency)
+	scaledMinIndividualUnitImpact, _ := utils.AdjustRateForTime(impact.MinimumIndividualUnitImpact, impact.ExpectedFrequency)
+	scaledMaxIndividualUnitImpact, _ := utils.AdjustRateForTime(impact.MaximumIndividualUnitImpact, impact.ExpectedFrequency)
+	scaledMinImpactEvents, _ := utils.AdjustRateForTime(impact.MinimumImpactEvents, impact.ExpectedFrequency)
+	scaledMaxImpactEvents, _ := utils.AdjustRateForTime(impact.MaximumImpactEvents, impact.ExpectedFrequency)
 
 	avgUnitImpact := weightedAverageWithConfidence(
 		scaledMinIndividualUnitImpact, impact.MaximumIndividualUnitImpact,
@@ -102,38 +148,39 @@ func adjustImpactBasedOnEventProbability(impact *risk.Impact, eventProbability f
 // MonteCarlo simulates the risk event network a specified number of times,
 // adjusting for dependencies using Bayesian statistics.
 func MonteCarlo(events []*risk.Event, iterations int) (map[int]float64, map[string]float64, error) {
-	eventProbabilities := make(map[int]float64)
-	totalImpacts := make(map[string]float64)
-	eventOccurrences := make(map[int]int)
-	impactOccurrences := make(map[string]int)
+	return MonteCarloSeeded(events, iterations, time.Now().UnixNano())
+}
+
+// MonteCarloSeeded is MonteCarlo with a fixed seed, so that repeated runs of the
+// same model produce the same results.
+func MonteCarloSeeded(events []*risk.Event, iterations int, seed int64) (map[int]float64, map[string]float64, error) {
+	return monteCarlo(events, iterations, seed, nil)
+}
 
-	// Initialize probabilities with a reasonable estimate
-	for _, event := range events {
-		scaledMin := utils.AdjustForTime(event.Probability.Minimum, event.Probability.ExpectedFrequency)
-		scaledMax := utils.AdjustForTime(event.Probability.Maximum, event.Probability.ExpectedFrequency)
-		initMin := statistics.GenerateBetaSample(scaledMin, event.Probability.MinimumConfidence)
-		initMax := statistics.GenerateBetaSample(scaledMax, event.Probability.MaximumConfidence)
-		sampleProb := statistics.GenerateLHSSamples(initMin, initMax, 100)
-		avgProb := calcAvg(sampleProb)
-		eventProbabilities[event.ID] = clampProbability(avgProb)
+func monteCarlo(events []*risk.Event, iterations int, seed int64, tracer *Tracer) (map[int]float64, map[string]float64, error) {
+	sim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, nil, err
 	}
+	sim.tracer = tracer
 
-	for i := 0; i < iterations; i++ {
-		eventsOccurred := make(map[int]bool)
-		rand.Seed(time.Now().UnixNano())
+	totalImpacts := make(map[string]float64)
+	eventOccurrences := make(map[int]int)
+	impactOccurrences := make(map[string]int)
 
-		for _, event := range events {
-			happened, impacts := SimulateEvent(event, eventsOccurred, eventProbabilities)
-			eventsOccurred[event.ID] = happened
+	sim.run(iterations, func(_ int, eventsOccurred map[int]bool, eventImpacts map[int]map[string]float64) {
+		for eventID, happened := range eventsOccurred {
 			if happened {
-				eventOccurrences[event.ID]++
-				for impactType, impactValue := range impacts {
-					totalImpacts[impactType] += impactValue
-					impactOccurrences[impactType]++ // Increment count for this impact type.
-				}
+				eventOccurrences[eventID]++
 			}
 		}
-	}
+		for _, impacts := range eventImpacts {
+			for impactType, impactValue := range impacts {
+				totalImpacts[impactType] += impactValue
+				impactOccurrences[impactType]++ // Increment count for this impact type.
+			}
+		}
+	})
 
 	// Normalize the total impacts based on the number of occurrences, not iterations.
 	for impactType, totalValue := range totalImpacts {
@@ -143,7 +190,8 @@ func MonteCarlo(events []*risk.Event, iterations int) (map[int]float64, map[stri
 	}
 
 	// Adjust probabilities based on occurrences
-	for eventID := range eventProbabilities {
+	eventProbabilities := make(map[int]float64)
+	for eventID := range sim.probabilities {
 		if occurrences, found := eventOccurrences[eventID]; found {
 			eventProbabilities[eventID] = float64(occurrences) / float64(iterations)
 		} else {
@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// reactionModel is a certain event whose only impact is a secondary loss that its stakeholders almost always react to.
+func reactionModel() []*risk.Event {
+	event := testEvent(1, "Breach", 1)
+	event.Impact[0].Secondary = &risk.SecondaryLoss{
+		Stakeholder:       "Regulator",
+		Minimum:           0.99,
+		MinimumConfidence: 0.9,
+		Maximum:           0.99,
+		MaximumConfidence: 0.9,
+	}
+	event.Impact[0].MaximumImpactEvents = 5
+	return []*risk.Event{event}
+}
+
+func TestReactionsDrawTheirOwnMagnitude(t *testing.T) {
+	sim, err := newSimulation(reactionModel(), testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[float64]bool)
+	for i := 0; i < 200; i++ {
+		o := sim.iterateOutcome(i)
+		loss, ok := o.secondary[1]["USD"]
+		if !ok {
+			continue
+		}
+		if o.impacts[1]["USD"] != loss {
+			t.Fatalf("iteration %d: impacts %v hold more than the secondary loss %f", i, o.impacts[1], loss)
+		}
+		if loss < 100 || loss > 5000 {
+			t.Fatalf("iteration %d: secondary loss %f outside the impact's bounds", i, loss)
+		}
+		seen[loss] = true
+	}
+	if len(seen) < 100 {
+		t.Errorf("only %d distinct secondary losses in 200 iterations", len(seen))
+	}
+}
+
+func TestSimulateEventDrawsReactions(t *testing.T) {
+	events := reactionModel()
+	reacted := 0
+	for i := 0; i < 100; i++ {
+		happened, impacts, err := SimulateEvent(events[0], make(map[int]bool), map[int]float64{1: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !happened {
+			t.Fatal("a certain event did not happen")
+		}
+		if impacts["USD"] > 0 {
+			reacted++
+		}
+	}
+	if reacted < 90 {
+		t.Errorf("stakeholders reacted to %d of 100 occurrences, expected about 99", reacted)
+	}
+
+	events[0].Impact[0].ExpectedFrequency = "fortnightly"
+	if _, _, err := SimulateEvent(events[0], make(map[int]bool), map[int]float64{1: 1}); err == nil {
+		t.Error("an impact with an unknown timeframe was accepted")
+	}
+}
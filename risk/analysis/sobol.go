@@ -0,0 +1,177 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/statistics"
+	"github.com/bcdannyboy/dgws/risk/utils"
+	"golang.org/x/exp/rand"
+	"gonum.org/v1/gonum/stat"
+)
+
+// SobolIndex is the share of an output's variance explained by one input.
+type SobolIndex struct {
+	Input Input  `json:"Input"`
+	Label string `json:"Label"`
+	// FirstOrder is the share of variance explained by the input alone.
+	FirstOrder float64 `json:"FirstOrder"`
+	// TotalEffect is the share of variance explained by the input including all its interactions.
+	TotalEffect float64 `json:"TotalEffect"`
+}
+
+// SobolOutput holds the indices of every input for one output, ranked by total effect.
+type SobolOutput struct {
+	Name     string        `json:"Name"`
+	Mean     float64       `json:"Mean"`
+	Variance float64       `json:"Variance"`
+	Indices  []*SobolIndex `json:"Indices"`
+}
+
+// Sobol is the result of a variance-based global sensitivity analysis.
+type Sobol struct {
+	Samples     int            `json:"Samples"`
+	Iterations  int            `json:"Iterations"`
+	Evaluations int            `json:"Evaluations"`
+	Outputs     []*SobolOutput `json:"Outputs"`
+}
+
+// SobolIndices estimates first-order and total-effect Sobol indices of every uncertain input of the model
+// (the inputs swung by OneAtATime with the given relative swing, drawn uniformly between their bounds) for the
+// top event's probability and for the expected yearly impact of each unit.
+// It uses Saltelli sampling with Latin Hypercube base matrices of the given number of samples, which requires
+// samples*(inputs+2) simulations of the given number of iterations, all run with common random numbers.
+func SobolIndices(events []*risk.Event, topEventID int, swing float64, samples, iterations int) (*Sobol, error) {
+	return SobolIndicesSeeded(events, topEventID, swing, samples, iterations, time.Now().UnixNano())
+}
+
+// SobolIndicesSeeded is SobolIndices with a fixed seed.
+func SobolIndicesSeeded(events []*risk.Event, topEventID int, swing float64, samples, iterations int, seed int64) (*Sobol, error) {
+	if samples < 2 {
+		return nil, fmt.Errorf("at least 2 samples are required, got %d", samples)
+	}
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	if err := validateSwing(swing); err != nil {
+		return nil, err
+	}
+	topEvent := utils.FindEvent(topEventID, events)
+	if topEvent == nil {
+		return nil, fmt.Errorf("top event %d not found", topEventID)
+	}
+
+	var inputs []inputBounds
+	for _, bounds := range uncertainInputs(events) {
+		bounds.min, bounds.max = bounds.swung(swing)
+		if bounds.min != bounds.max {
+			inputs = append(inputs, bounds)
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("the model has no uncertain inputs")
+	}
+
+	// Base matrices A and B, one Latin Hypercube column per input.
+	rng := rand.New(rand.NewSource(uint64(seed)))
+	a := make([][]float64, len(inputs))
+	b := make([][]float64, len(inputs))
+	for k, bounds := range inputs {
+		a[k] = statistics.GenerateLHSSamplesFrom(rng, bounds.min, bounds.max, samples)
+		b[k] = statistics.GenerateLHSSamplesFrom(rng, bounds.min, bounds.max, samples)
+	}
+
+	units := make(map[string]bool)
+	evaluations := 0
+	evaluate := func(column func(k int) []float64, j int) (map[string]float64, error) {
+		values := make(map[Input]float64, len(inputs))
+		for k, bounds := range inputs {
+			values[bounds.input] = column(k)[j]
+		}
+		modified, err := withInputs(events, values)
+		if err != nil {
+			return nil, err
+		}
+		sim, err := newSimulation(modified, seed)
+		if err != nil {
+			return nil, err
+		}
+		probabilities, impacts := expectedOutcomes(sim, iterations)
+		evaluations++
+
+		outputs := map[string]float64{"": probabilities[topEventID]}
+		for unit, value := range impacts {
+			units[unit] = true
+			outputs[unit] = value
+		}
+		return outputs, nil
+	}
+
+	fA := make([]map[string]float64, samples)
+	fB := make([]map[string]float64, samples)
+	fAB := make([][]map[string]float64, len(inputs))
+	for j := 0; j < samples; j++ {
+		var err error
+		if fA[j], err = evaluate(func(k int) []float64 { return a[k] }, j); err != nil {
+			return nil, err
+		}
+		if fB[j], err = evaluate(func(k int) []float64 { return b[k] }, j); err != nil {
+			return nil, err
+		}
+	}
+	for i := range inputs {
+		fAB[i] = make([]map[string]float64, samples)
+		// AB_i is A with its i-th column taken from B.
+		column := func(k int) []float64 {
+			if k == i {
+				return b[k]
+			}
+			return a[k]
+		}
+		for j := 0; j < samples; j++ {
+			var err error
+			if fAB[i][j], err = evaluate(column, j); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sobol := &Sobol{Samples: samples, Iterations: iterations, Evaluations: evaluations}
+	names := append([]string{""}, sortedKeys(units)...)
+	for _, name := range names {
+		output := &SobolOutput{Name: name}
+		if name == "" {
+			output.Name = topEvent.Name + " Probability"
+		}
+
+		all := make([]float64, 0, 2*samples)
+		for j := 0; j < samples; j++ {
+			all = append(all, fA[j][name], fB[j][name])
+		}
+		output.Mean, output.Variance = stat.MeanVariance(all, nil)
+
+		for i, bounds := range inputs {
+			index := &SobolIndex{Input: bounds.input, Label: bounds.label}
+			if output.Variance > 0 {
+				var first, total float64
+				for j := 0; j < samples; j++ {
+					// Saltelli (2010) first-order and Jansen total-effect estimators.
+					first += fB[j][name] * (fAB[i][j][name] - fA[j][name])
+					total += (fA[j][name] - fAB[i][j][name]) * (fA[j][name] - fAB[i][j][name])
+				}
+				index.FirstOrder = first / float64(samples) / output.Variance
+				index.TotalEffect = total / float64(2*samples) / output.Variance
+			}
+			output.Indices = append(output.Indices, index)
+		}
+		sort.SliceStable(output.Indices, func(x, y int) bool {
+			return output.Indices[x].TotalEffect > output.Indices[y].TotalEffect
+		})
+
+		sobol.Outputs = append(sobol.Outputs, output)
+	}
+
+	return sobol, nil
+}
@@ -0,0 +1,167 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/utils"
+)
+
+// TimeStep is one step of a time-stepped simulation.
+type TimeStep struct {
+	// Step counts from 1 for the first step of the year.
+	Step int `json:"Step"`
+	// EventProbabilities is the share of iterations in which each event occurred in this step.
+	EventProbabilities map[int]float64 `json:"EventProbabilities"`
+	// ExpectedOccurrences is the mean number of times each event occurred in this step.
+	ExpectedOccurrences map[int]float64 `json:"ExpectedOccurrences"`
+	// Impacts holds the distribution of each impact unit's total in this step, ordered by unit.
+	Impacts []*ImpactDistribution `json:"Impacts"`
+}
+
+// TimeSteps is the result of a time-stepped simulation.
+type TimeSteps struct {
+	Step       string      `json:"Step"`
+	Iterations int         `json:"Iterations"`
+	Series     []*TimeStep `json:"Series"`
+	// Yearly holds the distribution of each impact unit's total over all the steps of the year.
+	Yearly []*YearlyImpact `json:"Yearly"`
+}
+
+// MonteCarloSteps simulates every iteration as a year, as YearlyImpacts does, and spreads its occurrences
+// over steps of the year, "daily", "weekly", "monthly" or "quarterly", to report the series of each step's
+// occurrence probabilities, expected occurrences and impacts. Each occurrence of an event is placed at a time
+// drawn from the seasonality of its Probability or Frequency, uniform without one, and no earlier than the
+// first occurrence of the events it requires, so chains of events unfold forward in time. Impacts are
+// counted in the step of their occurrence, so the steps of an iteration add up to its yearly total and the
+// Yearly distributions match YearlyImpacts with the same seed.
+func MonteCarloSteps(events []*risk.Event, step string, iterations int) (*TimeSteps, error) {
+	return MonteCarloStepsSeeded(events, step, iterations, time.Now().UnixNano())
+}
+
+// MonteCarloStepsSeeded is MonteCarloSteps with a fixed seed.
+func MonteCarloStepsSeeded(events []*risk.Event, step string, iterations int, seed int64) (*TimeSteps, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("at least 1 iteration is required, got %d", iterations)
+	}
+	switch step {
+	case "daily", "weekly", "monthly", "quarterly":
+	default:
+		return nil, fmt.Errorf("unknown time step %q, expected daily, weekly, monthly or quarterly", step)
+	}
+	periods, _ := utils.PeriodsPerYear(step)
+	steps := int(periods)
+	sim, err := newSimulation(events, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	// impacts holds each unit's total per step and iteration, and totals and secondary its yearly totals.
+	impacts := make(map[string][][]float64)
+	totals := make(map[string][]float64)
+	secondary := make(map[string][]float64)
+	occurred := make([]map[int]int, steps)
+	occurrences := make([]map[int]int, steps)
+	for k := range occurred {
+		occurred[k] = make(map[int]int)
+		occurrences[k] = make(map[int]int)
+	}
+	record := func(unit string) {
+		if _, ok := impacts[unit]; ok {
+			return
+		}
+		impacts[unit] = make([][]float64, steps)
+		for k := range impacts[unit] {
+			impacts[unit][k] = make([]float64, iterations)
+		}
+		totals[unit] = make([]float64, iterations)
+		secondary[unit] = make([]float64, iterations)
+	}
+
+	for i := 0; i < iterations; i++ {
+		o := sim.iterateOutcome(i)
+		for unit, value := range totalImpacts(o.impacts) {
+			record(unit)
+			totals[unit][i] = value
+		}
+		for unit, value := range totalImpacts(o.secondary) {
+			record(unit)
+			secondary[unit][i] = value
+		}
+		for eventID, schedule := range sim.schedule(i, o) {
+			// Every occurrence of the event carries an equal share of its impacts.
+			count := float64(len(schedule))
+			seen := make(map[int]bool)
+			for _, t := range schedule {
+				k := int(t * periods)
+				if k >= steps {
+					k = steps - 1
+				}
+				occurrences[k][eventID]++
+				if !seen[k] {
+					seen[k] = true
+					occurred[k][eventID]++
+				}
+				for unit, value := range o.impacts[eventID] {
+					impacts[unit][k][i] += value / count
+				}
+			}
+		}
+	}
+
+	units := make(map[string]bool, len(impacts))
+	for unit := range impacts {
+		units[unit] = true
+	}
+	result := &TimeSteps{Step: step, Iterations: iterations}
+	for k := 0; k < steps; k++ {
+		s := &TimeStep{
+			Step:                k + 1,
+			EventProbabilities:  make(map[int]float64, len(events)),
+			ExpectedOccurrences: make(map[int]float64, len(events)),
+		}
+		for _, event := range events {
+			s.EventProbabilities[event.ID] = float64(occurred[k][event.ID]) / float64(iterations)
+			s.ExpectedOccurrences[event.ID] = float64(occurrences[k][event.ID]) / float64(iterations)
+		}
+		for _, unit := range sortedKeys(units) {
+			s.Impacts = append(s.Impacts, distribution(unit, impacts[unit][k]))
+		}
+		result.Series = append(result.Series, s)
+	}
+	for _, unit := range sortedKeys(units) {
+		result.Yearly = append(result.Yearly, yearlyImpact(unit, totals[unit], secondary[unit]))
+	}
+	return result, nil
+}
+
+// schedule places every occurrence of an iteration's outcome in time, as a fraction of the year, and returns
+// the times of each event's occurrences. Events are placed in model order, so the events an event requires
+// are placed before it and it follows the first occurrence of the last of them.
+func (s *simulation) schedule(iteration int, o *outcome) map[int][]float64 {
+	times := make(map[int][]float64)
+	first := make(map[int]float64)
+	for _, event := range s.events {
+		count := o.counts[event.ID]
+		if count == 0 {
+			continue
+		}
+		var from float64
+		for _, dependency := range event.Dependencies {
+			if t, ok := first[dependency.DependsOnEventID]; ok && dependency.Happens && t > from {
+				from = t
+			}
+		}
+		seasonality, _ := profile(event)
+		rng := s.rng(iteration, event.ID, streamTiming)
+		first[event.ID] = 1
+		for j := 0; j < count; j++ {
+			t := seasonalTime(seasonality, from, rng.Float64())
+			times[event.ID] = append(times[event.ID], t)
+			first[event.ID] = math.Min(first[event.ID], t)
+		}
+	}
+	return times
+}
@@ -0,0 +1,57 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+func TestStepsAddUpToYearlyResult(t *testing.T) {
+	const iterations = 5000
+	events := testModel()
+	events[0].Probability.Seasonality = &risk.Seasonality{Monthly: []float64{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 2, 4}}
+
+	steps, err := MonteCarloStepsSeeded(events, "monthly", iterations, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yearly, err := YearlyImpactsSeeded(events, PropagateEpisodes, iterations, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	occurrences, err := OccurrencesSeeded(events, PropagateEpisodes, iterations, testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps.Series) != 12 {
+		t.Fatalf("got %d monthly steps", len(steps.Series))
+	}
+
+	for u, impact := range yearly {
+		if steps.Yearly[u].Unit != impact.Unit || math.Abs(steps.Yearly[u].Total.Mean-impact.Total.Mean) > 1e-9 {
+			t.Errorf("yearly %s total %f, YearlyImpacts %f", steps.Yearly[u].Unit, steps.Yearly[u].Total.Mean, impact.Total.Mean)
+		}
+		var sum float64
+		for _, step := range steps.Series {
+			sum += step.Impacts[u].Mean
+		}
+		if math.Abs(sum-impact.Total.Mean) > 1e-9*math.Max(1, impact.Total.Mean) {
+			t.Errorf("%s steps add up to %f, yearly mean %f", impact.Unit, sum, impact.Total.Mean)
+		}
+	}
+	for _, occurrence := range occurrences {
+		var sum float64
+		for _, step := range steps.Series {
+			sum += step.ExpectedOccurrences[occurrence.EventID]
+		}
+		if math.Abs(sum-occurrence.Mean) > 1e-9 {
+			t.Errorf("event %d occurs %f times over the steps, %f times a year", occurrence.EventID, sum, occurrence.Mean)
+		}
+	}
+
+	// The threat's seasonality puts most of its occurrences at the end of the year.
+	if december, june := steps.Series[11].EventProbabilities[1], steps.Series[5].EventProbabilities[1]; december < 4*june {
+		t.Errorf("threat probability in December %f is not well above June %f", december, june)
+	}
+}
@@ -0,0 +1,183 @@
+package analysis
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// TraceFormat is the file format a Tracer writes.
+type TraceFormat string
+
+const (
+	// TraceJSONLines writes one JSON object per traced iteration.
+	TraceJSONLines TraceFormat = "jsonl"
+	// TraceCSV writes one row per event of each traced iteration.
+	TraceCSV TraceFormat = "csv"
+)
+
+// How an event's outcome was decided in a traced iteration.
+const (
+	TraceSampled  = "sampled"
+	TraceForced   = "forced"
+	TraceObserved = "observed"
+)
+
+// EventTrace records how one event was simulated in an iteration.
+type EventTrace struct {
+	EventID int    `json:"EventID"`
+	Name    string `json:"Name"`
+	// Occurrence lists, for an event simulated once per occurrence of a frequency event, the occurrence
+	// numbers of the enclosing frequency events.
+	Occurrence []int `json:"Occurrence,omitempty"`
+	// Probability is the event's sampled base probability.
+	Probability float64 `json:"Probability"`
+	// AdjustedProbability is the probability after UpdateEventProbabilityWithDependency (or forcing).
+	AdjustedProbability float64 `json:"AdjustedProbability"`
+	// Mode is TraceSampled, TraceForced or TraceObserved.
+	Mode string `json:"Mode"`
+	// Draw is the uniform random number compared against AdjustedProbability when the event is sampled.
+	Draw     float64 `json:"Draw"`
+	Occurred bool    `json:"Occurred"`
+	// Occurrences is the number of times the event occurred, which may exceed 1 for frequency events.
+	Occurrences int `json:"Occurrences"`
+	// Impacts are the event's primary impacts and SecondaryImpacts the secondary losses of the stakeholder
+	// reactions it triggered.
+	Impacts          map[string]float64 `json:"Impacts,omitempty"`
+	SecondaryImpacts map[string]float64 `json:"SecondaryImpacts,omitempty"`
+}
+
+// IterationTrace records every event of one iteration.
+type IterationTrace struct {
+	Iteration int `json:"Iteration"`
+	// Weight is the likelihood weight of the iteration, 1 unless the simulation is conditioned on evidence.
+	Weight float64       `json:"Weight"`
+	Events []*EventTrace `json:"Events"`
+}
+
+// Tracer writes per-iteration traces of a simulation for auditing and debugging.
+type Tracer struct {
+	format     TraceFormat
+	sampleRate float64
+	buffer     *bufio.Writer
+	csv        *csv.Writer
+	err        error
+}
+
+// NewTracer returns a tracer that writes to w in the given format. sampleRate is the share of iterations
+// traced, chosen at random from the simulation's seed; one traces every iteration and zero none.
+func NewTracer(w io.Writer, format TraceFormat, sampleRate float64) (*Tracer, error) {
+	if format != TraceJSONLines && format != TraceCSV {
+		return nil, fmt.Errorf("unknown trace format %q", format)
+	}
+	if sampleRate < 0 || sampleRate > 1 {
+		return nil, fmt.Errorf("sample rate must be between 0 and 1, got %f", sampleRate)
+	}
+	t := &Tracer{format: format, sampleRate: sampleRate, buffer: bufio.NewWriter(w)}
+	if format == TraceCSV {
+		t.csv = csv.NewWriter(t.buffer)
+		t.err = t.csv.Write([]string{"Iteration", "Weight", "EventID", "Event", "Occurrence", "Probability", "AdjustedProbability", "Mode", "Draw", "Occurred", "Occurrences", "Impacts", "SecondaryImpacts"})
+	}
+	return t, nil
+}
+
+// Flush writes any buffered trace and returns the first error encountered while tracing.
+func (t *Tracer) Flush() error {
+	if t.csv != nil {
+		t.csv.Flush()
+		if t.err == nil {
+			t.err = t.csv.Error()
+		}
+	}
+	if err := t.buffer.Flush(); t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
+
+// traces reports whether the iteration is traced. It is safe to call on a nil tracer.
+func (t *Tracer) traces(s *simulation, iteration int) bool {
+	if t == nil || t.err != nil || t.sampleRate == 0 {
+		return false
+	}
+	return t.sampleRate == 1 || s.uniform(iteration, 0, streamTrace) < t.sampleRate
+}
+
+func (t *Tracer) write(trace *IterationTrace) {
+	if t.err != nil {
+		return
+	}
+
+	if t.format == TraceJSONLines {
+		line, err := json.Marshal(trace)
+		if err == nil {
+			line = append(line, '\n')
+			_, err = t.buffer.Write(line)
+		}
+		t.err = err
+		return
+	}
+
+	for _, event := range trace.Events {
+		occurrence := make([]string, len(event.Occurrence))
+		for i, k := range event.Occurrence {
+			occurrence[i] = strconv.Itoa(k)
+		}
+
+		record := []string{
+			strconv.Itoa(trace.Iteration),
+			strconv.FormatFloat(trace.Weight, 'g', -1, 64),
+			strconv.Itoa(event.EventID),
+			event.Name,
+			strings.Join(occurrence, "/"),
+			strconv.FormatFloat(event.Probability, 'g', -1, 64),
+			strconv.FormatFloat(event.AdjustedProbability, 'g', -1, 64),
+			event.Mode,
+			strconv.FormatFloat(event.Draw, 'g', -1, 64),
+			strconv.FormatBool(event.Occurred),
+			strconv.Itoa(event.Occurrences),
+			formatImpacts(event.Impacts),
+			formatImpacts(event.SecondaryImpacts),
+		}
+		if t.err = t.csv.Write(record); t.err != nil {
+			return
+		}
+	}
+}
+
+// MonteCarloTraced is MonteCarloSeeded with a tracer recording the sampled iterations.
+// The tracer is flushed before returning and any error writing the trace is returned.
+func MonteCarloTraced(events []*risk.Event, iterations int, seed int64, tracer *Tracer) (map[int]float64, map[string]float64, error) {
+	if tracer == nil {
+		return nil, nil, fmt.Errorf("no tracer given")
+	}
+	probabilities, impacts, err := monteCarlo(events, iterations, seed, tracer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tracer.Flush(); err != nil {
+		return nil, nil, fmt.Errorf("error writing trace: %w", err)
+	}
+	return probabilities, impacts, nil
+}
+
+// formatImpacts formats impacts as unit=value pairs sorted by unit and separated by semicolons.
+func formatImpacts(impacts map[string]float64) string {
+	units := make([]string, 0, len(impacts))
+	for unit := range impacts {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	pairs := make([]string, len(units))
+	for i, unit := range units {
+		pairs[i] = unit + "=" + strconv.FormatFloat(impacts[unit], 'g', -1, 64)
+	}
+	return strings.Join(pairs, ";")
+}
@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTracedRunMatchesSeededRun(t *testing.T) {
+	for _, seed := range []int64{0, testSeed} {
+		var buffer bytes.Buffer
+		tracer, err := NewTracer(&buffer, TraceJSONLines, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		traced, _, err := MonteCarloTraced(testModel(), 100, seed, tracer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seeded, _, err := MonteCarloSeeded(testModel(), 100, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(traced, seeded) {
+			t.Errorf("seed %d: traced run gave %v, seeded run %v", seed, traced, seeded)
+		}
+		if lines := strings.Count(buffer.String(), "\n"); lines != 100 {
+			t.Errorf("seed %d: traced %d of 100 iterations at sample rate 1", seed, lines)
+		}
+	}
+}
+
+func TestZeroSampleRateTracesNothing(t *testing.T) {
+	var buffer bytes.Buffer
+	tracer, err := NewTracer(&buffer, TraceCSV, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := MonteCarloTraced(testModel(), 100, testSeed, tracer); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buffer.String(), "\n"); lines != 1 {
+		t.Errorf("wrote %d lines at sample rate 0, expected the header alone", lines)
+	}
+	if _, err := NewTracer(&buffer, TraceCSV, 1.5); err == nil {
+		t.Error("a sample rate above 1 was accepted")
+	}
+}
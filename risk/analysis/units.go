@@ -0,0 +1,67 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// NormalizeUnits returns a copy of events in which every impact's unit is replaced by its canonical name in
+// the registry, so that "USD", "usd" and "Dollars" are added together. Undeclared units are reported as errors.
+// Changed events and impacts are copied, so the caller's events are left untouched.
+func NormalizeUnits(events []*risk.Event, registry *risk.UnitRegistry) ([]*risk.Event, error) {
+	if err := registry.Validate(); err != nil {
+		return nil, err
+	}
+
+	normalized := make([]*risk.Event, len(events))
+	for i, event := range events {
+		normalized[i] = event
+		if event == nil {
+			continue
+		}
+		for j, impact := range event.Impact {
+			unit, err := registry.Canonical(impact.Unit)
+			if err != nil {
+				return nil, fmt.Errorf("event %d (%s) impact %s: %w", event.ID, event.Name, impact.Name, err)
+			}
+			if unit == impact.Unit {
+				continue
+			}
+			if normalized[i] == event {
+				clone := *event
+				clone.Impact = append([]*risk.Impact(nil), event.Impact...)
+				normalized[i] = &clone
+			}
+			impactCopy := *impact
+			impactCopy.Unit = unit
+			normalized[i].Impact[j] = &impactCopy
+		}
+	}
+	return normalized, nil
+}
+
+// SumImpacts adds impacts keyed by unit, such as the totals returned by MonteCarlo, after converting each to
+// the given unit. Impacts whose unit has a different dimension cannot be added and are reported as errors.
+func SumImpacts(impacts map[string]float64, registry *risk.UnitRegistry, unit string) (float64, error) {
+	if err := registry.Validate(); err != nil {
+		return 0, err
+	}
+	var sum float64
+	for _, from := range sortedKeys(unitSet(impacts)) {
+		value, err := registry.Convert(impacts[from], from, unit)
+		if err != nil {
+			return 0, err
+		}
+		sum += value
+	}
+	return sum, nil
+}
+
+func unitSet(impacts map[string]float64) map[string]bool {
+	units := make(map[string]bool, len(impacts))
+	for unit := range impacts {
+		units[unit] = true
+	}
+	return units
+}
@@ -0,0 +1,312 @@
+// Package fair models risk scenarios with the FAIR (Factor Analysis of Information Risk) taxonomy and
+// simulates them with the analysis engine.
+//
+// A scenario can be parameterized at any level of the taxonomy. Levels left unset are derived from the
+// levels below them:
+//
+//	Loss Event Frequency   = Threat Event Frequency x Vulnerability
+//	Threat Event Frequency = Contact Frequency x Probability of Action
+//	Vulnerability          = P(Threat Capability > Resistance Strength)
+package fair
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bcdannyboy/dgws/risk"
+	"github.com/bcdannyboy/dgws/risk/analysis"
+)
+
+// Form is one of the six FAIR forms of loss.
+type Form string
+
+const (
+	Productivity         Form = "Productivity"
+	Response             Form = "Response"
+	Replacement          Form = "Replacement"
+	FinesAndJudgments    Form = "Fines and Judgments"
+	CompetitiveAdvantage Form = "Competitive Advantage"
+	Reputation           Form = "Reputation"
+)
+
+// Forms returns the six forms of loss.
+func Forms() []Form {
+	return []Form{Productivity, Response, Replacement, FinesAndJudgments, CompetitiveAdvantage, Reputation}
+}
+
+// Estimate is a calibrated range with the confidence in each bound, as used by risk.Probability and risk.Impact.
+type Estimate struct {
+	Minimum           float64 `json:"Minimum"`
+	MinimumConfidence float64 `json:"MinimumConfidence"`
+	Maximum           float64 `json:"Maximum"`
+	MaximumConfidence float64 `json:"MaximumConfidence"`
+}
+
+// Loss is the magnitude of one form of loss per loss event.
+type Loss struct {
+	Form      Form      `json:"Form"`
+	Magnitude *Estimate `json:"Magnitude"`
+}
+
+// Scenario is a FAIR risk scenario. Frequencies are numbers of events per ExpectedFrequency period,
+// Vulnerability and Probability of Action are probabilities, and Threat Capability and Resistance Strength
+// are percentiles (0 to 100) of the threat community.
+type Scenario struct {
+	Name              string `json:"Name"`
+	Unit              string `json:"Unit"`
+	ExpectedFrequency string `json:"ExpectedFrequency"`
+	// Distribution and Dispersion select the count distribution of the frequencies, as in risk.Frequency.
+	Distribution string  `json:"Distribution,omitempty"`
+	Dispersion   float64 `json:"Dispersion,omitempty"`
+
+	LossEventFrequency   *Estimate `json:"LossEventFrequency,omitempty"`
+	ThreatEventFrequency *Estimate `json:"ThreatEventFrequency,omitempty"`
+	ContactFrequency     *Estimate `json:"ContactFrequency,omitempty"`
+	ProbabilityOfAction  *Estimate `json:"ProbabilityOfAction,omitempty"`
+	Vulnerability        *Estimate `json:"Vulnerability,omitempty"`
+	ThreatCapability     *Estimate `json:"ThreatCapability,omitempty"`
+	ResistanceStrength   *Estimate `json:"ResistanceStrength,omitempty"`
+
+	PrimaryLoss []*Loss `json:"PrimaryLoss"`
+
+	// SecondaryLossEventFrequency is the probability that the secondary stakeholders react to a loss event,
+	// and SecondaryLoss the magnitude of each form of loss their reaction causes.
+	SecondaryStakeholder        string    `json:"SecondaryStakeholder,omitempty"`
+	SecondaryLossEventFrequency *Estimate `json:"SecondaryLossEventFrequency,omitempty"`
+	SecondaryLoss               []*Loss   `json:"SecondaryLoss,omitempty"`
+}
+
+// Derive returns a copy of the scenario in which every unset level that can be computed from the levels
+// below it is filled in. Products of ranges multiply their bounds and keep the lower confidence; the
+// vulnerability is the probability that a threat capability drawn uniformly from its range exceeds a
+// resistance strength drawn uniformly from its range, with the average confidence of the two.
+func (s *Scenario) Derive() *Scenario {
+	derived := *s
+	if derived.ThreatEventFrequency == nil && derived.ContactFrequency != nil && derived.ProbabilityOfAction != nil {
+		derived.ThreatEventFrequency = product(derived.ContactFrequency, derived.ProbabilityOfAction)
+	}
+	if derived.Vulnerability == nil && derived.ThreatCapability != nil && derived.ResistanceStrength != nil {
+		v := exceedance(derived.ThreatCapability, derived.ResistanceStrength)
+		derived.Vulnerability = &Estimate{
+			Minimum:           v,
+			MinimumConfidence: (derived.ThreatCapability.MinimumConfidence + derived.ResistanceStrength.MinimumConfidence) / 2,
+			Maximum:           v,
+			MaximumConfidence: (derived.ThreatCapability.MaximumConfidence + derived.ResistanceStrength.MaximumConfidence) / 2,
+		}
+	}
+	if derived.LossEventFrequency == nil && derived.ThreatEventFrequency != nil && derived.Vulnerability != nil {
+		derived.LossEventFrequency = product(derived.ThreatEventFrequency, derived.Vulnerability)
+	}
+	return &derived
+}
+
+// Events builds the event tree of the scenario, numbering its events from firstID.
+// When the scenario gives its loss event frequency, the tree is a single loss event with that frequency;
+// otherwise it is a threat event with the threat event frequency followed, for each threat event, by a loss
+// event with the vulnerability as its probability. The forms of loss are impacts of the loss event, the
+// secondary ones only occurring when the secondary stakeholders react.
+func (s *Scenario) Events(firstID int) ([]*risk.Event, error) {
+	if s.Unit == "" {
+		return nil, fmt.Errorf("scenario %s has no unit", s.Name)
+	}
+	derived := s.Derive()
+
+	lossEvent := &risk.Event{
+		ID:          firstID,
+		Name:        s.Name + " Loss Event",
+		Description: "A threat event that results in loss.",
+	}
+	for i, loss := range s.PrimaryLoss {
+		impact, err := s.impact(i+1, loss)
+		if err != nil {
+			return nil, err
+		}
+		lossEvent.Impact = append(lossEvent.Impact, impact)
+	}
+	if len(s.SecondaryLoss) > 0 && s.SecondaryLossEventFrequency == nil {
+		return nil, fmt.Errorf("scenario %s has secondary losses without a secondary loss event frequency", s.Name)
+	}
+	for i, loss := range s.SecondaryLoss {
+		impact, err := s.impact(len(s.PrimaryLoss)+i+1, loss)
+		if err != nil {
+			return nil, err
+		}
+		impact.Name = "Secondary " + impact.Name
+		impact.Secondary = &risk.SecondaryLoss{
+			Stakeholder:       s.SecondaryStakeholder,
+			Minimum:           s.SecondaryLossEventFrequency.Minimum,
+			MinimumConfidence: s.SecondaryLossEventFrequency.MinimumConfidence,
+			Maximum:           s.SecondaryLossEventFrequency.Maximum,
+			MaximumConfidence: s.SecondaryLossEventFrequency.MaximumConfidence,
+		}
+		lossEvent.Impact = append(lossEvent.Impact, impact)
+	}
+
+	if s.LossEventFrequency != nil {
+		lossEvent.Frequency = s.frequency(s.LossEventFrequency)
+		return []*risk.Event{lossEvent}, nil
+	}
+	if derived.ThreatEventFrequency == nil || derived.Vulnerability == nil {
+		return nil, fmt.Errorf("scenario %s needs a loss event frequency, or a threat event frequency (or contact frequency and probability of action) and a vulnerability (or threat capability and resistance strength)", s.Name)
+	}
+
+	threatEvent := &risk.Event{
+		ID:          firstID,
+		Name:        s.Name + " Threat Event",
+		Description: "A threat agent acts against the asset.",
+		Frequency:   s.frequency(derived.ThreatEventFrequency),
+	}
+	lossEvent.ID = firstID + 1
+	lossEvent.Probability = &risk.Probability{
+		Kind:              risk.KindProbability,
+		ExpectedFrequency: "yearly",
+		Minimum:           derived.Vulnerability.Minimum,
+		MinimumConfidence: derived.Vulnerability.MinimumConfidence,
+		Maximum:           derived.Vulnerability.Maximum,
+		MaximumConfidence: derived.Vulnerability.MaximumConfidence,
+	}
+	lossEvent.Dependencies = []*risk.Dependency{{DependsOnEventID: threatEvent.ID, Happens: true}}
+	return []*risk.Event{threatEvent, lossEvent}, nil
+}
+
+func (s *Scenario) frequency(estimate *Estimate) *risk.Frequency {
+	return &risk.Frequency{
+		Distribution:      s.Distribution,
+		ExpectedFrequency: s.ExpectedFrequency,
+		Minimum:           estimate.Minimum,
+		MinimumConfidence: estimate.MinimumConfidence,
+		Maximum:           estimate.Maximum,
+		MaximumConfidence: estimate.MaximumConfidence,
+		Dispersion:        s.Dispersion,
+	}
+}
+
+// impact is the loss per loss event of one form of loss.
+func (s *Scenario) impact(id int, loss *Loss) (*risk.Impact, error) {
+	if loss == nil || loss.Magnitude == nil {
+		return nil, fmt.Errorf("scenario %s has a loss without a magnitude", s.Name)
+	}
+	return &risk.Impact{
+		ImpactID:                              id,
+		Name:                                  string(loss.Form),
+		Unit:                                  s.Unit,
+		Description:                           string(loss.Form) + " loss per loss event.",
+		ExpectedFrequency:                     "yearly",
+		MinimumIndividualUnitImpact:           loss.Magnitude.Minimum,
+		MinimumIndividualUnitImpactConfidence: loss.Magnitude.MinimumConfidence,
+		MaximumIndividualUnitImpact:           loss.Magnitude.Maximum,
+		MaximumIndividualUnitImpactConfidence: loss.Magnitude.MaximumConfidence,
+		MinimumImpactEvents:                   1,
+		MinimumImpactEventsConfidence:         1,
+		MaximumImpactEvents:                   1,
+		MaximumImpactEventsConfidence:         1,
+	}, nil
+}
+
+// Result is the simulated loss exposure of a scenario.
+type Result struct {
+	// Scenario is the scenario with its derived levels filled in.
+	Scenario   *Scenario `json:"Scenario"`
+	Iterations int       `json:"Iterations"`
+	// LossEvents is the distribution of the yearly number of loss events.
+	LossEvents *analysis.OccurrenceDistribution `json:"LossEvents"`
+	// AnnualizedLossExposure is the distribution of the yearly total loss in the scenario's unit,
+	// and PrimaryLoss and SecondaryLoss the distributions of its primary and secondary parts.
+	AnnualizedLossExposure *analysis.ImpactDistribution `json:"AnnualizedLossExposure"`
+	PrimaryLoss            *analysis.ImpactDistribution `json:"PrimaryLoss"`
+	SecondaryLoss          *analysis.ImpactDistribution `json:"SecondaryLoss"`
+}
+
+// Simulate builds the scenario's event tree and simulates it to estimate its annualized loss exposure.
+func Simulate(s *Scenario, iterations int) (*Result, error) {
+	return SimulateSeeded(s, iterations, time.Now().UnixNano())
+}
+
+// SimulateSeeded is Simulate with a fixed seed.
+func SimulateSeeded(s *Scenario, iterations int, seed int64) (*Result, error) {
+	events, err := s.Events(1)
+	if err != nil {
+		return nil, err
+	}
+	lossEvent := events[len(events)-1]
+
+	occurrences, impacts, err := analysis.YearlyOutcomesSeeded(events, analysis.PropagateEpisodes, iterations, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Scenario:               s.Derive(),
+		Iterations:             iterations,
+		AnnualizedLossExposure: &analysis.ImpactDistribution{Unit: s.Unit},
+		PrimaryLoss:            &analysis.ImpactDistribution{Unit: s.Unit},
+		SecondaryLoss:          &analysis.ImpactDistribution{Unit: s.Unit},
+	}
+	for _, distribution := range occurrences {
+		if distribution.EventID == lossEvent.ID {
+			result.LossEvents = distribution
+		}
+	}
+	for _, impact := range impacts {
+		if impact.Unit == s.Unit {
+			result.AnnualizedLossExposure = impact.Total
+			result.PrimaryLoss = impact.Primary
+			result.SecondaryLoss = impact.Secondary
+		}
+	}
+	return result, nil
+}
+
+// product multiplies two ranges bound by bound, keeping the lower confidence of each bound.
+func product(a, b *Estimate) *Estimate {
+	return &Estimate{
+		Minimum:           a.Minimum * b.Minimum,
+		MinimumConfidence: minFloat(a.MinimumConfidence, b.MinimumConfidence),
+		Maximum:           a.Maximum * b.Maximum,
+		MaximumConfidence: minFloat(a.MaximumConfidence, b.MaximumConfidence),
+	}
+}
+
+// exceedance is the probability that a value drawn uniformly from the capability range exceeds a value
+// drawn uniformly from the resistance range.
+func exceedance(capability, resistance *Estimate) float64 {
+	// P(capability > r) for a fixed resistance r.
+	above := func(r float64) float64 {
+		if capability.Maximum <= capability.Minimum {
+			if capability.Minimum > r {
+				return 1
+			}
+			return 0
+		}
+		return clamp((capability.Maximum-r)/(capability.Maximum-capability.Minimum), 0, 1)
+	}
+	if resistance.Maximum <= resistance.Minimum {
+		return above(resistance.Minimum)
+	}
+
+	// Midpoint rule over the resistance range.
+	const steps = 1000
+	width := (resistance.Maximum - resistance.Minimum) / steps
+	var p float64
+	for i := 0; i < steps; i++ {
+		p += above(resistance.Minimum + (float64(i)+0.5)*width)
+	}
+	return p / steps
+}
+
+func clamp(value, min, max float64) float64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
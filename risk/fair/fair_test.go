@@ -0,0 +1,68 @@
+package fair
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bcdannyboy/dgws/risk/analysis"
+)
+
+func testScenario() *Scenario {
+	return &Scenario{
+		Name:                 "Breach",
+		Unit:                 "USD",
+		ExpectedFrequency:    "yearly",
+		ThreatEventFrequency: &Estimate{Minimum: 1, MinimumConfidence: 0.9, Maximum: 4, MaximumConfidence: 0.9},
+		Vulnerability:        &Estimate{Minimum: 0.2, MinimumConfidence: 0.9, Maximum: 0.4, MaximumConfidence: 0.9},
+		PrimaryLoss: []*Loss{
+			{Form: Response, Magnitude: &Estimate{Minimum: 1000, MinimumConfidence: 0.9, Maximum: 5000, MaximumConfidence: 0.9}},
+		},
+		SecondaryStakeholder:        "Regulator",
+		SecondaryLossEventFrequency: &Estimate{Minimum: 0.1, MinimumConfidence: 0.9, Maximum: 0.3, MaximumConfidence: 0.9},
+		SecondaryLoss: []*Loss{
+			{Form: FinesAndJudgments, Magnitude: &Estimate{Minimum: 10000, MinimumConfidence: 0.9, Maximum: 50000, MaximumConfidence: 0.9}},
+		},
+	}
+}
+
+func TestSimulateMatchesSeparateRuns(t *testing.T) {
+	const iterations, seed = 2000, 42
+	scenario := testScenario()
+	result, err := SimulateSeeded(scenario, iterations, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := scenario.Events(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	occurrences, err := analysis.OccurrencesSeeded(events, analysis.PropagateEpisodes, iterations, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	impacts, err := analysis.YearlyImpactsSeeded(events, analysis.PropagateEpisodes, iterations, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := occurrences[len(occurrences)-1]; !reflect.DeepEqual(result.LossEvents, want) {
+		t.Errorf("loss events %+v, separate run %+v", result.LossEvents, want)
+	}
+	if !reflect.DeepEqual(result.AnnualizedLossExposure, impacts[0].Total) || !reflect.DeepEqual(result.SecondaryLoss, impacts[0].Secondary) {
+		t.Errorf("loss exposure %+v, separate run %+v", result.AnnualizedLossExposure, impacts[0].Total)
+	}
+	if result.SecondaryLoss.Mean <= 0 {
+		t.Error("no secondary loss was simulated")
+	}
+}
+
+func TestFormsCannotBeModified(t *testing.T) {
+	forms := Forms()
+	if len(forms) != 6 {
+		t.Fatalf("got %d forms of loss, want 6", len(forms))
+	}
+	forms[0] = "Tampered"
+	if Forms()[0] != Productivity {
+		t.Errorf("modifying the returned forms changed them to %v", Forms())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"math/rand"
 	"time"
 
+	xrand "golang.org/x/exp/rand"
 	"gonum.org/v1/gonum/stat/distuv"
 )
 
@@ -12,6 +13,13 @@ import (
 func GenerateBetaSample(p float64, c float64) float64 {
 	rand.Seed(time.Now().UnixNano())
 
+	return GenerateBetaSampleFrom(nil, p, c)
+}
+
+// GenerateBetaSampleFrom generates a sample from a beta distribution
+// for a given probability p and confidence level c using the given source.
+// A nil source uses the global source.
+func GenerateBetaSampleFrom(src xrand.Source, p float64, c float64) float64 {
 	// Ensure p is within valid range for a beta distribution
 	if p <= 0 {
 		p = 0.01 // Assign a small probability if p is less or equal to 0
@@ -29,7 +37,7 @@ func GenerateBetaSample(p float64, c float64) float64 {
 	beta := (1 - p) * c
 
 	// Create and sample from the beta distribution
-	betaDist := distuv.Beta{Alpha: alpha, Beta: beta}
+	betaDist := distuv.Beta{Alpha: alpha, Beta: beta, Src: src}
 	sample := betaDist.Rand()
 
 	return sample
@@ -48,3 +56,28 @@ func GenerateLHSSamples(min, max float64, n int) []float64 {
 	rand.Shuffle(len(samples), func(i, j int) { samples[i], samples[j] = samples[j], samples[i] })
 	return samples
 }
+
+// GenerateLHSSamplesFrom generates Latin Hypercube Samples for a given range and sample size
+// using the given random number generator.
+func GenerateLHSSamplesFrom(rng *xrand.Rand, min, max float64, n int) []float64 {
+	step := (max - min) / float64(n)
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = rng.Float64()*step + float64(i)*step + min
+	}
+
+	rng.Shuffle(len(samples), func(i, j int) { samples[i], samples[j] = samples[j], samples[i] })
+	return samples
+}
+
+// GeneratePERTSampleFrom generates a sample from a PERT distribution with the given minimum,
+// most likely value and maximum using the given random number generator.
+func GeneratePERTSampleFrom(rng *xrand.Rand, min, mode, max float64) float64 {
+	if max <= min {
+		return min
+	}
+	alpha := 1 + 4*(mode-min)/(max-min)
+	beta := 1 + 4*(max-mode)/(max-min)
+	betaDist := distuv.Beta{Alpha: alpha, Beta: beta, Src: rng}
+	return min + betaDist.Rand()*(max-min)
+}
@@ -1,11 +1,69 @@
 package risk
 
+// Kinds of Probability. A probability is the chance of the event in one ExpectedFrequency period,
+// a rate is the expected number of events per period.
+const (
+	KindProbability = "probability"
+	KindRate        = "rate"
+)
+
 type Probability struct {
+	Kind              string  `json:"Kind,omitempty"`
 	ExpectedFrequency string  `json:"ExpectedFrequency"`
 	Minimum           float64 `json:"Minimum"`
 	MinimumConfidence float64 `json:"MinimumConfidence"`
 	Maximum           float64 `json:"Maximum"`
 	MaximumConfidence float64 `json:"MaximumConfidence"`
+	// Seasonality and Trend vary the probability within a year and across the years of a horizon.
+	Seasonality *Seasonality `json:"Seasonality,omitempty"`
+	Trend       *Trend       `json:"Trend,omitempty"`
+}
+
+// Seasonality scales an estimate by calendar month, such as phishing peaking around the holidays.
+// Monthly holds 12 multipliers from January to December. A whole year is scaled by their mean,
+// so multipliers that average to 1 keep the yearly estimate and only move occurrences within the year.
+type Seasonality struct {
+	Monthly []float64 `json:"Monthly"`
+}
+
+// Trend scales an estimate from one year of a multi-year horizon to the next.
+// Yearly holds the multiplier of each year from the first, and later years keep its last multiplier.
+// Without Yearly, year n is scaled by (1+Growth)^(n-1).
+type Trend struct {
+	Growth float64   `json:"Growth,omitempty"`
+	Yearly []float64 `json:"Yearly,omitempty"`
+}
+
+// Count distributions of a Frequency.
+const (
+	DistributionPoisson          = "poisson"
+	DistributionNegativeBinomial = "negativebinomial"
+)
+
+// Frequency is the rate of an event that can occur many times a year, such as phishing emails.
+// Minimum and Maximum are the expected number of occurrences per ExpectedFrequency period.
+type Frequency struct {
+	Distribution      string  `json:"Distribution"`
+	ExpectedFrequency string  `json:"ExpectedFrequency"`
+	Minimum           float64 `json:"Minimum"`
+	MinimumConfidence float64 `json:"MinimumConfidence"`
+	Maximum           float64 `json:"Maximum"`
+	MaximumConfidence float64 `json:"MaximumConfidence"`
+	// Dispersion is the shape of a negative binomial count: the yearly count has variance mean + mean^2/Dispersion.
+	Dispersion float64 `json:"Dispersion,omitempty"`
+	// Seasonality and Trend vary the rate within a year and across the years of a horizon.
+	Seasonality *Seasonality `json:"Seasonality,omitempty"`
+	Trend       *Trend       `json:"Trend,omitempty"`
+}
+
+// SecondaryLoss is the chance that secondary stakeholders, such as regulators or customers, react to an
+// occurrence of the event. Minimum and Maximum bound the probability of a reaction per occurrence.
+type SecondaryLoss struct {
+	Stakeholder       string  `json:"Stakeholder"`
+	Minimum           float64 `json:"Minimum"`
+	MinimumConfidence float64 `json:"MinimumConfidence"`
+	Maximum           float64 `json:"Maximum"`
+	MaximumConfidence float64 `json:"MaximumConfidence"`
 }
 
 type Impact struct {
@@ -16,6 +74,8 @@ type Impact struct {
 
 	Description       string `json:"Description"`
 	ExpectedFrequency string `json:"ExpectedFrequency"`
+	// Secondary, when set, makes this a secondary loss that only occurs when its stakeholders react.
+	Secondary *SecondaryLoss `json:"Secondary,omitempty"`
 
 	MinimumIndividualUnitImpact           float64 `json:"MinimumIndividualUnitImpact"`
 	MinimumIndividualUnitImpactConfidence float64 `json:"MinimumIndividualUnitImpactConfidence"`
@@ -33,10 +93,13 @@ type Dependency struct {
 }
 
 type Event struct {
-	ID           int           `json:"ID"`
-	Name         string        `json:"Name"`
-	Description  string        `json:"Description"`
-	Probability  *Probability  `json:"Probability"`
+	ID          int          `json:"ID"`
+	Name        string       `json:"Name"`
+	Description string       `json:"Description"`
+	Probability *Probability `json:"Probability"`
+	// Frequency, set instead of Probability, lets the event occur several times in a simulated year.
+	Frequency    *Frequency    `json:"Frequency,omitempty"`
+	Control      bool          `json:"Control,omitempty"`
 	Impact       []*Impact     `json:"Impact,omitempty"`
 	Dependencies []*Dependency `json:"Dependencies,omitempty"`
 }
@@ -0,0 +1,166 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValueFactor is one uncertain factor of the monetary value of a unit, such as the analyst hours spent on an
+// alert or the hourly rate of an analyst. MostLikely defaults to the midpoint of Minimum and Maximum.
+type ValueFactor struct {
+	Name       string  `json:"Name"`
+	Minimum    float64 `json:"Minimum"`
+	MostLikely float64 `json:"MostLikely,omitempty"`
+	Maximum    float64 `json:"Maximum"`
+}
+
+// Monetization converts a non-monetary impact unit to money: one unit is worth the product of its factors
+// in Currency, e.g. 0.5 to 2 analyst hours per "Host Control Alert" times $60 to $120 per hour.
+type Monetization struct {
+	Unit     string         `json:"Unit"`
+	Currency string         `json:"Currency"`
+	Factors  []*ValueFactor `json:"Factors"`
+}
+
+// UnitRegistry declares the impact units of a model, how they convert to each other, and their monetary value.
+type UnitRegistry struct {
+	Units         []*UnitDefinition `json:"Units,omitempty"`
+	Monetizations []*Monetization   `json:"Monetizations"`
+}
+
+// Monetization returns how the unit is converted to money, or nil if it is not registered.
+// Declared units also match the monetizations of their aliases.
+func (r *UnitRegistry) Monetization(unit string) *Monetization {
+	definition := r.Unit(unit)
+	for _, monetization := range r.Monetizations {
+		if monetization.Unit == unit || (definition != nil && r.Unit(monetization.Unit) == definition) {
+			return monetization
+		}
+	}
+	return nil
+}
+
+// Dimension is the kind of quantity a unit measures. Only units of the same dimension can be added together.
+type Dimension string
+
+const (
+	DimensionMoney   Dimension = "money"
+	DimensionTime    Dimension = "time"
+	DimensionCount   Dimension = "count"
+	DimensionRecords Dimension = "records"
+)
+
+// UnitDefinition declares a canonical unit. A unit with a Base is worth Factor of its Base unit, e.g. a day is
+// 24 hours or a EUR is 1.08 USD at the configured rate, and must have the same dimension as its Base.
+type UnitDefinition struct {
+	Name      string    `json:"Name"`
+	Dimension Dimension `json:"Dimension"`
+	// Aliases are other spellings of the unit, e.g. "Dollars" for "USD". Names and aliases are matched
+	// regardless of case.
+	Aliases []string `json:"Aliases,omitempty"`
+	Base    string   `json:"Base,omitempty"`
+	Factor  float64  `json:"Factor,omitempty"`
+}
+
+// Unit returns the definition of a unit by its name or one of its aliases, or nil if it is not declared.
+func (r *UnitRegistry) Unit(unit string) *UnitDefinition {
+	for _, definition := range r.Units {
+		if strings.EqualFold(definition.Name, unit) {
+			return definition
+		}
+		for _, alias := range definition.Aliases {
+			if strings.EqualFold(alias, unit) {
+				return definition
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks that names and aliases are unique, and that every base unit is declared, has the same
+// dimension and does not lead back to the unit.
+func (r *UnitRegistry) Validate() error {
+	names := make(map[string]string)
+	for _, definition := range r.Units {
+		if definition == nil || definition.Name == "" {
+			return fmt.Errorf("unit without a name in unit registry")
+		}
+		if definition.Dimension == "" {
+			return fmt.Errorf("unit %s has no dimension", definition.Name)
+		}
+		for _, name := range append([]string{definition.Name}, definition.Aliases...) {
+			if other, ok := names[strings.ToLower(name)]; ok {
+				return fmt.Errorf("unit name %s is used by both %s and %s", name, other, definition.Name)
+			}
+			names[strings.ToLower(name)] = definition.Name
+		}
+	}
+
+	for _, definition := range r.Units {
+		if _, _, err := r.base(definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Canonical returns the canonical name of a unit.
+func (r *UnitRegistry) Canonical(unit string) (string, error) {
+	definition := r.Unit(unit)
+	if definition == nil {
+		return "", fmt.Errorf("unknown unit %q", unit)
+	}
+	return definition.Name, nil
+}
+
+// Convert converts a value from one unit to another of the same dimension.
+func (r *UnitRegistry) Convert(value float64, from, to string) (float64, error) {
+	fromDefinition, toDefinition := r.Unit(from), r.Unit(to)
+	if fromDefinition == nil {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	if toDefinition == nil {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+	if fromDefinition.Dimension != toDefinition.Dimension {
+		return 0, fmt.Errorf("cannot convert %s (%s) to %s (%s)", fromDefinition.Name, fromDefinition.Dimension, toDefinition.Name, toDefinition.Dimension)
+	}
+
+	fromRoot, fromFactor, err := r.base(fromDefinition)
+	if err != nil {
+		return 0, err
+	}
+	toRoot, toFactor, err := r.base(toDefinition)
+	if err != nil {
+		return 0, err
+	}
+	if fromRoot != toRoot {
+		return 0, fmt.Errorf("no conversion between %s and %s", fromDefinition.Name, toDefinition.Name)
+	}
+	return value * fromFactor / toFactor, nil
+}
+
+// base follows a unit's chain of base units and returns the last one with the unit's worth in it.
+func (r *UnitRegistry) base(definition *UnitDefinition) (*UnitDefinition, float64, error) {
+	factor := 1.0
+	visited := map[*UnitDefinition]bool{definition: true}
+	for definition.Base != "" {
+		if definition.Factor <= 0 {
+			return nil, 0, fmt.Errorf("unit %s needs a positive factor to its base %s", definition.Name, definition.Base)
+		}
+		base := r.Unit(definition.Base)
+		if base == nil {
+			return nil, 0, fmt.Errorf("unit %s has unknown base unit %q", definition.Name, definition.Base)
+		}
+		if base.Dimension != definition.Dimension {
+			return nil, 0, fmt.Errorf("unit %s (%s) cannot be based on %s (%s)", definition.Name, definition.Dimension, base.Name, base.Dimension)
+		}
+		if visited[base] {
+			return nil, 0, fmt.Errorf("unit %s is defined in terms of itself", base.Name)
+		}
+		visited[base] = true
+		factor *= definition.Factor
+		definition = base
+	}
+	return definition, factor, nil
+}
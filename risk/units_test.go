@@ -0,0 +1,66 @@
+package risk
+
+import (
+	"math"
+	"testing"
+)
+
+func testRegistry() *UnitRegistry {
+	return &UnitRegistry{Units: []*UnitDefinition{
+		{Name: "USD", Dimension: DimensionMoney, Aliases: []string{"Dollars"}},
+		{Name: "EUR", Dimension: DimensionMoney, Base: "USD", Factor: 1.08},
+		{Name: "Hours", Dimension: DimensionTime},
+		{Name: "Days", Dimension: DimensionTime, Base: "Hours", Factor: 24},
+		{Name: "Weeks", Dimension: DimensionTime, Base: "Days", Factor: 7},
+		{Name: "Records", Dimension: DimensionRecords},
+	}}
+}
+
+func TestConvert(t *testing.T) {
+	registry := testRegistry()
+	if err := registry.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value    float64
+		from, to string
+		want     float64
+	}{
+		{100, "EUR", "USD", 108},
+		{108, "dollars", "EUR", 100},
+		{2, "Weeks", "Hours", 336},
+		{48, "Hours", "Days", 2},
+		{5, "Records", "records", 5},
+	}
+	for _, test := range tests {
+		got, err := registry.Convert(test.value, test.from, test.to)
+		if err != nil {
+			t.Errorf("%f %s to %s: %v", test.value, test.from, test.to, err)
+		} else if math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%f %s to %s: got %f, want %f", test.value, test.from, test.to, got, test.want)
+		}
+	}
+
+	for _, invalid := range [][2]string{{"USD", "Hours"}, {"GBP", "USD"}, {"USD", "GBP"}} {
+		if _, err := registry.Convert(1, invalid[0], invalid[1]); err == nil {
+			t.Errorf("converting %s to %s was accepted", invalid[0], invalid[1])
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := map[string][]*UnitDefinition{
+		"unnamed":         {{Dimension: DimensionMoney}},
+		"no dimension":    {{Name: "USD"}},
+		"duplicate alias": {{Name: "USD", Dimension: DimensionMoney}, {Name: "Dollars", Dimension: DimensionMoney, Aliases: []string{"usd"}}},
+		"unknown base":    {{Name: "EUR", Dimension: DimensionMoney, Base: "USD", Factor: 1.08}},
+		"zero factor":     {{Name: "USD", Dimension: DimensionMoney}, {Name: "EUR", Dimension: DimensionMoney, Base: "USD"}},
+		"mixed dimension": {{Name: "Hours", Dimension: DimensionTime}, {Name: "USD", Dimension: DimensionMoney, Base: "Hours", Factor: 60}},
+		"cycle":           {{Name: "A", Dimension: DimensionCount, Base: "B", Factor: 2}, {Name: "B", Dimension: DimensionCount, Base: "A", Factor: 0.5}},
+	}
+	for name, units := range invalid {
+		if err := (&UnitRegistry{Units: units}).Validate(); err == nil {
+			t.Errorf("%s: registry was accepted", name)
+		}
+	}
+}
@@ -1,27 +1,90 @@
 package utils
 
+import (
+	"fmt"
+	"math"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+// AdjustForTime scales a value linearly to a yearly basis. Unknown timeframes are returned unchanged.
+//
+// Deprecated: use AdjustRateForTime, which reports unknown timeframes as errors.
 func AdjustForTime(Value float64, TimeFrame string) float64 {
+	periods, err := PeriodsPerYear(TimeFrame)
+	if err != nil {
+		return Value
+	}
+	return Value * periods
+}
+
+// PeriodsPerYear returns how many periods of the timeframe make up a year.
+// An empty timeframe is treated as yearly.
+func PeriodsPerYear(TimeFrame string) (float64, error) {
 	// we scale everything up  or down to a yearly basis
 	switch TimeFrame {
 	case "hourly":
-		return Value * 8760
+		return 8760, nil
 	case "daily":
-		return Value * 365
+		return 365, nil
 	case "weekly":
-		return Value * 52
+		return 52, nil
 	case "monthly":
-		return Value * 12
+		return 12, nil
 	case "quarterly":
-		return Value * 4
-	case "yearly":
-		return Value
+		return 4, nil
+	case "yearly", "":
+		return 1, nil
 	case "2years":
-		return Value / 2
+		return 1.0 / 2, nil
 	case "5years":
-		return Value / 5
+		return 1.0 / 5, nil
 	case "10years":
-		return Value / 10
+		return 1.0 / 10, nil
 	default:
-		return Value
+		return 0, fmt.Errorf("unknown frequency %q", TimeFrame)
+	}
+}
+
+// AdjustRateForTime scales a rate (events per period) linearly to events per year.
+func AdjustRateForTime(Value float64, TimeFrame string) (float64, error) {
+	periods, err := PeriodsPerYear(TimeFrame)
+	if err != nil {
+		return 0, err
+	}
+	return Value * periods, nil
+}
+
+// AdjustProbabilityForTime converts the probability of an event in one period to the probability of
+// at least one occurrence in a year, 1-(1-p)^n for n periods per year.
+func AdjustProbabilityForTime(Value float64, TimeFrame string) (float64, error) {
+	if Value < 0 || Value > 1 {
+		return 0, fmt.Errorf("probability %f is not between 0 and 1", Value)
+	}
+	periods, err := PeriodsPerYear(TimeFrame)
+	if err != nil {
+		return 0, err
+	}
+	return 1 - math.Pow(1-Value, periods), nil
+}
+
+// AnnualProbability converts a probability bound of the given kind and timeframe to the probability of
+// at least one occurrence in a year. Rates are scaled to a yearly rate and converted assuming Poisson
+// arrivals, 1-e^(-rate). An empty kind is treated as a probability.
+func AnnualProbability(Value float64, Kind string, TimeFrame string) (float64, error) {
+	switch Kind {
+	case risk.KindProbability, "":
+		return AdjustProbabilityForTime(Value, TimeFrame)
+	case risk.KindRate:
+		if Value < 0 {
+			return 0, fmt.Errorf("rate %f is negative", Value)
+		}
+		rate, err := AdjustRateForTime(Value, TimeFrame)
+		if err != nil {
+			return 0, err
+		}
+		return 1 - math.Exp(-rate), nil
+	default:
+		return 0, fmt.Errorf("unknown probability kind %q", Kind)
 	}
 }
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bcdannyboy/dgws/risk"
+)
+
+func TestPeriodsPerYear(t *testing.T) {
+	for timeFrame, want := range map[string]float64{
+		"":          1,
+		"yearly":    1,
+		"monthly":   12,
+		"quarterly": 4,
+		"weekly":    52,
+		"daily":     365,
+		"hourly":    8760,
+		"2years":    0.5,
+		"10years":   0.1,
+	} {
+		got, err := PeriodsPerYear(timeFrame)
+		if err != nil {
+			t.Errorf("%q: %v", timeFrame, err)
+		} else if got != want {
+			t.Errorf("%q: got %f periods per year, want %f", timeFrame, got, want)
+		}
+	}
+	if _, err := PeriodsPerYear("fortnightly"); err == nil {
+		t.Error("an unknown timeframe was accepted")
+	}
+	if _, err := AdjustRateForTime(1, "fortnightly"); err == nil {
+		t.Error("a rate with an unknown timeframe was accepted")
+	}
+}
+
+func TestAnnualProbability(t *testing.T) {
+	tests := []struct {
+		value     float64
+		kind      string
+		timeFrame string
+		want      float64
+	}{
+		{0.3, risk.KindProbability, "yearly", 0.3},
+		{0.3, "", "yearly", 0.3},
+		{0.1, risk.KindProbability, "monthly", 1 - math.Pow(0.9, 12)},
+		{0.5, risk.KindProbability, "2years", 1 - math.Sqrt(0.5)},
+		{1, risk.KindProbability, "daily", 1},
+		{2, risk.KindRate, "yearly", 1 - math.Exp(-2)},
+		{0.25, risk.KindRate, "quarterly", 1 - math.Exp(-1)},
+		{0, risk.KindRate, "weekly", 0},
+	}
+	for _, test := range tests {
+		got, err := AnnualProbability(test.value, test.kind, test.timeFrame)
+		if err != nil {
+			t.Errorf("%f %q %q: %v", test.value, test.kind, test.timeFrame, err)
+		} else if math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("%f %q %q: got %f, want %f", test.value, test.kind, test.timeFrame, got, test.want)
+		}
+	}
+
+	for _, invalid := range []struct {
+		value     float64
+		kind      string
+		timeFrame string
+	}{
+		{1.5, risk.KindProbability, "yearly"},
+		{-0.1, risk.KindProbability, "yearly"},
+		{-1, risk.KindRate, "yearly"},
+		{0.5, "odds", "yearly"},
+		{0.5, risk.KindProbability, "fortnightly"},
+	} {
+		if _, err := AnnualProbability(invalid.value, invalid.kind, invalid.timeFrame); err == nil {
+			t.Errorf("%f %q %q was accepted", invalid.value, invalid.kind, invalid.timeFrame)
+		}
+	}
+}